@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+	"net/http"
+	"strings"
+	"time"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+// defaultAllocationTtl is the time allocated player slots stay reserved, the players are expected to connect and be
+// reported by the game server within it
+const defaultAllocationTtl = time.Minute
+
+// AllocationRequest selects the game server to allocate players to, empty fields match any game server
+type AllocationRequest struct {
+	WorldId    *uuid.UUID `json:"worldId,omitempty"`
+	ReleaseId  *uuid.UUID `json:"releaseId,omitempty"`
+	RegionId   *uuid.UUID `json:"regionId,omitempty"`
+	GameModeId *uuid.UUID `json:"gameModeId,omitempty"`
+	// Labels of the game server resource
+	Selector *metaV1.LabelSelector `json:"selector,omitempty"`
+	// Number of player slots to reserve, 1 if empty
+	Players int32 `json:"players,omitempty"`
+}
+
+// AllocationResponse is the game server the players have been allocated to
+type AllocationResponse struct {
+	Id        uuid.UUID `json:"id"`
+	Host      string    `json:"host"`
+	Port      int32     `json:"port"`
+	WorldId   uuid.UUID `json:"worldId"`
+	ReleaseId uuid.UUID `json:"releaseId"`
+	// Ports are the public ports of the game server by name, including the unreal server port
+	Ports []AllocationPort `json:"ports,omitempty"`
+}
+
+// AllocationPort is a public port of the allocated game server
+type AllocationPort struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Host     string `json:"host"`
+	Port     int32  `json:"port"`
+}
+
+// Allocator serves the allocation endpoint, which selects a ready game server with free player slots and reserves the
+// slots, so concurrent clients are not sent to the same almost full game server
+type Allocator struct {
+	gameServerLister veverseListers.GameServerLister
+	ttl              time.Duration
+	// bearer token required by the endpoint, the endpoint is not authenticated if empty
+	token string
+}
+
+func NewAllocator(gameServerLister veverseListers.GameServerLister, ttl time.Duration, token string) *Allocator {
+	return &Allocator{
+		gameServerLister: gameServerLister,
+		ttl:              ttl,
+		token:            token,
+	}
+}
+
+// ServeHTTP handles POST requests with an allocation request body
+func (a *Allocator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJsonError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if a.token != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+			writeJsonError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+	}
+
+	var request AllocationRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&request)
+	if err == nil {
+		err = request.validate()
+	}
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+
+	response, err := a.Allocate(r.Context(), &request)
+	if err != nil {
+		Logger.Errorf("failed to allocate game server: %v", err)
+		writeJsonError(w, http.StatusInternalServerError, "failed to allocate game server")
+		return
+	}
+
+	if response == nil {
+		writeJsonError(w, http.StatusNotFound, "no game server available")
+		return
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// Allocate reserves player slots on the fullest matching game server which can take the players, returns nil if no game
+// server is available
+func (a *Allocator) Allocate(ctx context.Context, request *AllocationRequest) (*AllocationResponse, error) {
+	namespace := ctx.Value("namespace").(string)
+
+	if request.Players == 0 {
+		request.Players = 1
+	}
+
+	selector := labels.Everything()
+	if request.Selector != nil {
+		var err error
+		selector, err = metaV1.LabelSelectorAsSelector(request.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+
+	candidates, err := GetAllocationCandidates(ctx, request, a.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		// only game servers managed by the operator can be allocated, their status reflects the allocation
+		gameServer, err := a.gameServerLister.GameServers(namespace).Get(candidate.Id.String())
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get game server: %v", err)
+		}
+		if gameServer.DeletionTimestamp != nil || !selector.Matches(labels.Set(gameServer.Labels)) {
+			continue
+		}
+
+		// another allocation may have taken the free slots since the candidates have been selected
+		gameServerRecord, err := AllocateGameServer(ctx, candidate.Id, request.Players, a.ttl)
+		if err != nil {
+			return nil, err
+		}
+		if gameServerRecord == nil {
+			continue
+		}
+
+		Logger.Infof("allocated %d players to game server %s", request.Players, candidate.Id)
+
+		// the database reservation is authoritative, the status only reports it
+		err = setGameServerAllocatedStatus(ctx, candidate.Id, request.Players, a.ttl)
+		if err != nil {
+			Logger.Warningf("failed to update allocation status of game server %s: %v", candidate.Id, err)
+		}
+
+		response := &AllocationResponse{
+			Id:        gameServerRecord.Id,
+			Host:      gameServerRecord.Host,
+			Port:      gameServerRecord.Port,
+			WorldId:   gameServerRecord.WorldId,
+			ReleaseId: gameServerRecord.ReleaseId,
+		}
+
+		// the slots are reserved already, clients of a single port game server connect with the host and port alone
+		ports, err := GetGameServerPublicPorts(ctx, candidate.Id)
+		if err != nil {
+			Logger.Warningf("failed to get ports of game server %s: %v", candidate.Id, err)
+		}
+		for _, port := range ports {
+			response.Ports = append(response.Ports, AllocationPort{
+				Name:     port.Name,
+				Protocol: port.Protocol,
+				Host:     port.Host,
+				Port:     port.Port,
+			})
+		}
+
+		return response, nil
+	}
+
+	return nil, nil
+}
+
+// validate checks the request fields which can not be checked by decoding
+func (r *AllocationRequest) validate() error {
+	if r.Players < 0 {
+		return fmt.Errorf("players must not be negative")
+	}
+
+	if r.Selector != nil {
+		_, err := metaV1.LabelSelectorAsSelector(r.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// setGameServerAllocatedStatus adds the allocated players to the game server status, expired allocations are dropped like
+// in the database
+func setGameServerAllocatedStatus(ctx context.Context, id uuid.UUID, players int32, ttl time.Duration) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gameServer, err := veverseClientset.VeverseV1().GameServers(namespace).Get(ctx, id.String(), metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		now := metaV1.Now()
+		if gameServer.Status.AllocatedAt == nil || now.Sub(gameServer.Status.AllocatedAt.Time) > ttl {
+			gameServer.Status.AllocatedPlayers = 0
+		}
+		gameServer.Status.AllocatedPlayers += players
+		gameServer.Status.AllocatedAt = &now
+
+		_, err = veverseClientset.VeverseV1().GameServers(namespace).UpdateStatus(ctx, gameServer, metaV1.UpdateOptions{})
+		return err
+	})
+}
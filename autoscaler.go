@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"strings"
+	"time"
+	// time zones of schedule windows, the runtime image has no zoneinfo
+	_ "time/tzdata"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+	veverseInformers "veverse-server-operator/pkg/client/informers/externalversions/veverse/v1"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+const (
+	// defaultAutoscalerInterval is the time between two checks of the fleet autoscalers
+	defaultAutoscalerInterval = 30 * time.Second
+	// defaultScaleUpCooldown is the time to wait after scaling before scaling up again
+	defaultScaleUpCooldown = 30 * time.Second
+	// defaultScaleDownCooldown is the time to wait after scaling before scaling down again
+	defaultScaleDownCooldown = 5 * time.Minute
+)
+
+// FleetAutoscalerDecision is an entry of the fleet autoscaler decision log
+type FleetAutoscalerDecision struct {
+	Namespace       string
+	Autoscaler      string
+	Fleet           string
+	CurrentReplicas int32
+	DesiredReplicas int32
+	Players         int64
+	Capacity        int64
+	Schedule        string
+	Applied         bool
+	Reason          string
+}
+
+// fleetAutoscalerBounds are the buffer and bounds of the autoscaler, overridden by the active schedule window
+type fleetAutoscalerBounds struct {
+	BufferSize  intstr.IntOrString
+	MinReplicas int32
+	MaxReplicas int32
+	Schedule    string
+}
+
+// Autoscaler periodically scales game server fleets to keep the buffer of free player slots of their fleet autoscalers
+type Autoscaler struct {
+	autoscalerLister veverseListers.FleetAutoscalerLister
+	autoscalerSynced cache.InformerSynced
+	fleetLister      veverseListers.GameServerFleetLister
+	fleetSynced      cache.InformerSynced
+	gameServerLister veverseListers.GameServerLister
+	gameServerSynced cache.InformerSynced
+	interval         time.Duration
+}
+
+func NewAutoscaler(autoscalerInformer veverseInformers.FleetAutoscalerInformer, fleetInformer veverseInformers.GameServerFleetInformer, gameServerInformer veverseInformers.GameServerInformer, interval time.Duration) *Autoscaler {
+	return &Autoscaler{
+		autoscalerLister: autoscalerInformer.Lister(),
+		autoscalerSynced: autoscalerInformer.Informer().HasSynced,
+		fleetLister:      fleetInformer.Lister(),
+		fleetSynced:      fleetInformer.Informer().HasSynced,
+		gameServerLister: gameServerInformer.Lister(),
+		gameServerSynced: gameServerInformer.Informer().HasSynced,
+		interval:         interval,
+	}
+}
+
+// Run checks the fleet autoscalers every interval until the context is cancelled
+func (a *Autoscaler) Run(ctx context.Context) {
+	if !cache.WaitForCacheSync(ctx.Done(), a.autoscalerSynced, a.fleetSynced, a.gameServerSynced) {
+		Logger.Errorf("failed to sync fleet autoscaler informer caches, fleet autoscaler is not running")
+		return
+	}
+
+	Logger.Infof("fleet autoscaler interval: %v", a.interval)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		namespace := ctx.Value("namespace").(string)
+
+		autoscalers, err := a.autoscalerLister.FleetAutoscalers(namespace).List(labels.Everything())
+		if err != nil {
+			Logger.Errorf("failed to list fleet autoscalers: %v", err)
+			return
+		}
+
+		for _, autoscaler := range autoscalers {
+			start := time.Now()
+			err = a.Reconcile(ctx, autoscaler, start)
+			observeReconcile("fleet_autoscaler", start, err)
+			if err != nil {
+				Logger.Errorf("failed to reconcile fleet autoscaler %s: %v", autoscaler.Name, err)
+			}
+		}
+	}, a.interval)
+}
+
+// Reconcile computes the desired replicas of the fleet from its occupancy and scales the fleet unless a cooldown is active
+func (a *Autoscaler) Reconcile(ctx context.Context, autoscaler *veverseV1.FleetAutoscaler, now time.Time) error {
+	status := autoscaler.Status.DeepCopy()
+	status.ObservedGeneration = autoscaler.Generation
+
+	reconcileErr := a.reconcileFleet(ctx, autoscaler, status, now)
+	if reconcileErr != nil {
+		setFleetAutoscalerCondition(status, veverseV1.FleetAutoscalerConditionAbleToScale, metaV1.ConditionFalse, "FailedScale", reconcileErr.Error())
+	} else {
+		setFleetAutoscalerCondition(status, veverseV1.FleetAutoscalerConditionAbleToScale, metaV1.ConditionTrue, "AbleToScale", "fleet occupancy has been read")
+	}
+
+	// status reflects failed reconciles as well, so it is updated regardless of the reconcile result
+	err := updateFleetAutoscalerStatus(ctx, autoscaler, status)
+	if reconcileErr != nil {
+		return reconcileErr
+	}
+
+	return err
+}
+
+func (a *Autoscaler) reconcileFleet(ctx context.Context, autoscaler *veverseV1.FleetAutoscaler, status *veverseV1.FleetAutoscalerStatus, now time.Time) error {
+	bounds, err := getFleetAutoscalerBounds(&autoscaler.Spec, now)
+	if err != nil {
+		return err
+	}
+	status.ActiveSchedule = bounds.Schedule
+
+	fleet, err := a.fleetLister.GameServerFleets(autoscaler.Namespace).Get(autoscaler.Spec.FleetName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("fleet %s not found", autoscaler.Spec.FleetName)
+		}
+		return fmt.Errorf("failed to get fleet: %v", err)
+	}
+
+	//region Occupancy
+	gameServers, err := listFleetGameServers(a.gameServerLister, fleet)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, 0, len(gameServers))
+	for _, gameServer := range gameServers {
+		if id, err := getGameServerId(gameServer); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	players, capacity, err := GetGameServerOccupancy(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	status.Players = players
+	status.Capacity = capacity
+	status.CurrentReplicas = fleet.Spec.Replicas
+	//endregion
+
+	//region Desired replicas
+	// player slots of a new game server, taken from the template or observed on the running game servers
+	slots := fleet.Spec.Template.Settings.Players.Max
+	if slots <= 0 && len(ids) > 0 {
+		slots = capacity / int64(len(ids))
+	}
+	if slots <= 0 {
+		return fmt.Errorf("fleet %s has no max players set in its template", fleet.Name)
+	}
+
+	required, err := getRequiredCapacity(players, bounds.BufferSize)
+	if err != nil {
+		return err
+	}
+
+	unbounded := int32((required + slots - 1) / slots)
+	desired := unbounded
+	if desired < bounds.MinReplicas {
+		desired = bounds.MinReplicas
+	}
+	if desired > bounds.MaxReplicas {
+		desired = bounds.MaxReplicas
+	}
+	status.DesiredReplicas = desired
+
+	if desired != unbounded {
+		setFleetAutoscalerCondition(status, veverseV1.FleetAutoscalerConditionScalingLimited, metaV1.ConditionTrue, "Limited", fmt.Sprintf("%d replicas required, limited to %d", unbounded, desired))
+	} else {
+		setFleetAutoscalerCondition(status, veverseV1.FleetAutoscalerConditionScalingLimited, metaV1.ConditionFalse, "NotLimited", "desired replicas are within the bounds")
+	}
+	//endregion
+
+	if desired == fleet.Spec.Replicas {
+		return nil
+	}
+
+	//region Cooldown
+	reason := fmt.Sprintf("%d players, %d of %d slots free, buffer %s, %d slots per game server", players, capacity-players, capacity, bounds.BufferSize.String(), slots)
+	if bounds.Schedule != "" {
+		reason += fmt.Sprintf(", schedule %s", bounds.Schedule)
+	}
+	if desired != unbounded {
+		reason += fmt.Sprintf(", limited from %d replicas", unbounded)
+	}
+
+	cooldown := getFleetAutoscalerCooldown(&autoscaler.Spec, desired > fleet.Spec.Replicas)
+	if status.LastScaleTime != nil && now.Sub(status.LastScaleTime.Time) < cooldown {
+		reason = fmt.Sprintf("not scaling from %d to %d during cooldown of %v: %s", fleet.Spec.Replicas, desired, cooldown, reason)
+
+		// blocked decisions are only logged once, they are repeated on every check during the cooldown
+		if reason != status.LastDecision {
+			status.LastDecision = reason
+			return a.logDecision(ctx, autoscaler, status, bounds, false, reason)
+		}
+		return nil
+	}
+	//endregion
+
+	reason = fmt.Sprintf("scaling from %d to %d: %s", fleet.Spec.Replicas, desired, reason)
+	Logger.Infof("fleet autoscaler %s is %s", autoscaler.Name, reason)
+
+	err = scaleFleetClusterResource(ctx, fleet, desired)
+	if err != nil {
+		return err
+	}
+
+	lastScaleTime := metaV1.NewTime(now)
+	status.LastScaleTime = &lastScaleTime
+	status.LastDecision = reason
+
+	return a.logDecision(ctx, autoscaler, status, bounds, true, reason)
+}
+
+func (a *Autoscaler) logDecision(ctx context.Context, autoscaler *veverseV1.FleetAutoscaler, status *veverseV1.FleetAutoscalerStatus, bounds fleetAutoscalerBounds, applied bool, reason string) error {
+	return InsertFleetAutoscalerDecision(ctx, &FleetAutoscalerDecision{
+		Namespace:       autoscaler.Namespace,
+		Autoscaler:      autoscaler.Name,
+		Fleet:           autoscaler.Spec.FleetName,
+		CurrentReplicas: status.CurrentReplicas,
+		DesiredReplicas: status.DesiredReplicas,
+		Players:         status.Players,
+		Capacity:        status.Capacity,
+		Schedule:        bounds.Schedule,
+		Applied:         applied,
+		Reason:          reason,
+	})
+}
+
+// getRequiredCapacity returns the player slots required to keep the buffer free, a percentage buffer is the share of the
+// capacity which has to stay free
+func getRequiredCapacity(players int64, bufferSize intstr.IntOrString) (int64, error) {
+	if bufferSize.Type == intstr.Int {
+		if bufferSize.IntVal < 0 {
+			return 0, fmt.Errorf("buffer size must not be negative")
+		}
+		return players + int64(bufferSize.IntVal), nil
+	}
+
+	percent, err := intstr.GetScaledValueFromIntOrPercent(&bufferSize, 100, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse buffer size: %v", err)
+	}
+	if percent < 0 || percent >= 100 {
+		return 0, fmt.Errorf("buffer size percentage must be between 0%% and 99%%")
+	}
+
+	return (players*100 + int64(100-percent) - 1) / int64(100-percent), nil
+}
+
+// getFleetAutoscalerCooldown returns the time to wait after scaling before scaling in the given direction
+func getFleetAutoscalerCooldown(spec *veverseV1.FleetAutoscalerSpec, up bool) time.Duration {
+	if up {
+		if spec.ScaleUpCooldownSeconds > 0 {
+			return time.Duration(spec.ScaleUpCooldownSeconds) * time.Second
+		}
+		return defaultScaleUpCooldown
+	}
+
+	if spec.ScaleDownCooldownSeconds > 0 {
+		return time.Duration(spec.ScaleDownCooldownSeconds) * time.Second
+	}
+	return defaultScaleDownCooldown
+}
+
+// getFleetAutoscalerBounds returns the buffer and bounds of the autoscaler, overridden by the first active schedule window
+func getFleetAutoscalerBounds(spec *veverseV1.FleetAutoscalerSpec, now time.Time) (fleetAutoscalerBounds, error) {
+	bounds := fleetAutoscalerBounds{
+		BufferSize:  spec.BufferSize,
+		MinReplicas: spec.MinReplicas,
+		MaxReplicas: spec.MaxReplicas,
+	}
+
+	for _, schedule := range spec.Schedules {
+		active, err := isFleetAutoscalerScheduleActive(&schedule, now)
+		if err != nil {
+			return bounds, fmt.Errorf("invalid schedule %s: %v", schedule.Name, err)
+		}
+		if !active {
+			continue
+		}
+
+		bounds.Schedule = schedule.Name
+		if schedule.BufferSize != nil {
+			bounds.BufferSize = *schedule.BufferSize
+		}
+		if schedule.MinReplicas != nil {
+			bounds.MinReplicas = *schedule.MinReplicas
+		}
+		if schedule.MaxReplicas != nil {
+			bounds.MaxReplicas = *schedule.MaxReplicas
+		}
+		break
+	}
+
+	if bounds.MinReplicas < 0 || bounds.MaxReplicas < bounds.MinReplicas {
+		return bounds, fmt.Errorf("invalid replica bounds %d..%d", bounds.MinReplicas, bounds.MaxReplicas)
+	}
+
+	return bounds, nil
+}
+
+// isFleetAutoscalerScheduleActive checks if the schedule window contains the time
+func isFleetAutoscalerScheduleActive(schedule *veverseV1.FleetAutoscalerSchedule, now time.Time) (bool, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(schedule.TimeZone)
+		if err != nil {
+			return false, fmt.Errorf("failed to load time zone: %v", err)
+		}
+	}
+	now = now.In(location)
+
+	start, err := parseScheduleTime(schedule.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseScheduleTime(schedule.End)
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	// windows spanning midnight belong to the day they start on
+	var inWindow bool
+	if start <= end {
+		inWindow = minute >= start && minute < end
+	} else if minute >= start {
+		inWindow = true
+	} else if minute < end {
+		inWindow = true
+		day = (day + 6) % 7
+	}
+	if !inWindow {
+		return false, nil
+	}
+
+	if len(schedule.Days) == 0 {
+		return true, nil
+	}
+	for _, scheduleDay := range schedule.Days {
+		if strings.EqualFold(scheduleDay, day.String()[:3]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parseScheduleTime parses a HH:MM time of day into minutes since midnight
+func parseScheduleTime(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse time of day %q: %v", value, err)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func scaleFleetClusterResource(ctx context.Context, fleet *veverseV1.GameServerFleet, replicas int32) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	fleet = fleet.DeepCopy()
+	fleet.Spec.Replicas = replicas
+
+	_, err := veverseClientset.VeverseV1().GameServerFleets(namespace).Update(ctx, fleet, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to scale fleet: %v", err)
+	}
+
+	return nil
+}
+
+// updateFleetAutoscalerStatus writes the status to the fleet autoscaler status subresource if it has changed
+func updateFleetAutoscalerStatus(ctx context.Context, autoscaler *veverseV1.FleetAutoscaler, status *veverseV1.FleetAutoscalerStatus) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	if equality.Semantic.DeepEqual(status, &autoscaler.Status) {
+		return nil
+	}
+
+	autoscaler = autoscaler.DeepCopy()
+	autoscaler.Status = *status
+
+	_, err := veverseClientset.VeverseV1().FleetAutoscalers(namespace).UpdateStatus(ctx, autoscaler, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update fleet autoscaler status: %v", err)
+	}
+
+	return nil
+}
+
+// setFleetAutoscalerCondition sets the condition on the status, the transition time only changes if the condition status changes
+func setFleetAutoscalerCondition(status *veverseV1.FleetAutoscalerStatus, conditionType string, conditionStatus metaV1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metaV1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: status.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"testing"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+func TestGetRequiredCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		players    int64
+		bufferSize intstr.IntOrString
+		want       int64
+		wantErr    bool
+	}{
+		{name: "slots", players: 10, bufferSize: intstr.FromInt(5), want: 15},
+		{name: "no slots", players: 0, bufferSize: intstr.FromInt(0), want: 0},
+		{name: "negative slots", players: 10, bufferSize: intstr.FromInt(-1), wantErr: true},
+		{name: "percentage exact", players: 80, bufferSize: intstr.FromString("20%"), want: 100},
+		// 81 of 101 slots would leave 19.8% free
+		{name: "percentage rounded up", players: 81, bufferSize: intstr.FromString("20%"), want: 102},
+		{name: "percentage not divisible", players: 10, bufferSize: intstr.FromString("33%"), want: 15},
+		{name: "zero percent", players: 7, bufferSize: intstr.FromString("0%"), want: 7},
+		{name: "maximum percentage", players: 1, bufferSize: intstr.FromString("99%"), want: 100},
+		{name: "no players", players: 0, bufferSize: intstr.FromString("50%"), want: 0},
+		{name: "full percentage", players: 1, bufferSize: intstr.FromString("100%"), wantErr: true},
+		{name: "negative percentage", players: 1, bufferSize: intstr.FromString("-10%"), wantErr: true},
+		{name: "not a percentage", players: 1, bufferSize: intstr.FromString("20"), wantErr: true},
+		{name: "malformed percentage", players: 1, bufferSize: intstr.FromString("abc%"), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := getRequiredCapacity(test.players, test.bufferSize)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsFleetAutoscalerScheduleActive(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.January, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		schedule veverseV1.FleetAutoscalerSchedule
+		now      time.Time
+		want     bool
+		wantErr  bool
+	}{
+		{name: "inside window", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00"}, now: at(1, 12, 0), want: true},
+		{name: "start is inclusive", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00"}, now: at(1, 9, 0), want: true},
+		{name: "end is exclusive", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00"}, now: at(1, 17, 0), want: false},
+		{name: "before window", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00"}, now: at(1, 8, 59), want: false},
+		{name: "empty window", schedule: veverseV1.FleetAutoscalerSchedule{Start: "10:00", End: "10:00"}, now: at(1, 10, 0), want: false},
+		{name: "matching day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Mon"}, Start: "09:00", End: "17:00"}, now: at(1, 12, 0), want: true},
+		{name: "day is case insensitive", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"mon"}, Start: "09:00", End: "17:00"}, now: at(1, 12, 0), want: true},
+		{name: "other day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Tue", "Wed"}, Start: "09:00", End: "17:00"}, now: at(1, 12, 0), want: false},
+		{name: "midnight window before midnight", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Fri"}, Start: "22:00", End: "02:00"}, now: at(5, 23, 0), want: true},
+		{name: "midnight window after midnight belongs to previous day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Fri"}, Start: "22:00", End: "02:00"}, now: at(6, 1, 0), want: true},
+		{name: "midnight window on next day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Fri"}, Start: "22:00", End: "02:00"}, now: at(6, 23, 0), want: false},
+		{name: "midnight window after midnight of day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Fri"}, Start: "22:00", End: "02:00"}, now: at(5, 1, 0), want: false},
+		{name: "midnight window from sunday to monday", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Sun"}, Start: "22:00", End: "02:00"}, now: at(1, 1, 0), want: true},
+		{name: "midnight window outside", schedule: veverseV1.FleetAutoscalerSchedule{Start: "22:00", End: "02:00"}, now: at(1, 12, 0), want: false},
+		{name: "time zone inside window", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00", TimeZone: "Europe/Berlin"}, now: at(1, 8, 30), want: true},
+		{name: "time zone outside window", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00", TimeZone: "Europe/Berlin"}, now: at(1, 16, 30), want: false},
+		// monday 03:00 UTC is sunday 19:00 in Los Angeles
+		{name: "time zone day", schedule: veverseV1.FleetAutoscalerSchedule{Days: []string{"Sun"}, Start: "18:00", End: "20:00", TimeZone: "America/Los_Angeles"}, now: at(1, 3, 0), want: true},
+		{name: "unknown time zone", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: "17:00", TimeZone: "Mars/Olympus"}, now: at(1, 12, 0), wantErr: true},
+		{name: "invalid start", schedule: veverseV1.FleetAutoscalerSchedule{Start: "25:00", End: "17:00"}, now: at(1, 12, 0), wantErr: true},
+		{name: "invalid end", schedule: veverseV1.FleetAutoscalerSchedule{Start: "09:00", End: ""}, now: at(1, 12, 0), wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := isFleetAutoscalerScheduleActive(&test.schedule, test.now)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseScheduleTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "00:00", want: 0},
+		{value: "09:30", want: 570},
+		{value: "23:59", want: 1439},
+		{value: "24:00", wantErr: true},
+		{value: "12:60", wantErr: true},
+		{value: "noon", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := parseScheduleTime(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
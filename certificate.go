@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// selfSignedCertificateValidity is the validity of the certificates generated by the operator
+	selfSignedCertificateValidity = 365 * 24 * time.Hour
+	// selfSignedCertificateRenewBefore is the time before expiry the certificate is replaced
+	selfSignedCertificateRenewBefore = 30 * 24 * time.Hour
+	// selfSignedCertificateCheckInterval is the time between two checks of the certificate secret
+	selfSignedCertificateCheckInterval = time.Hour
+	// previousCertKey is the key of the certificate secret with the replaced certificate, which stays in the CA bundle
+	// until it expires, so instances which have not picked up the renewed certificate yet are still trusted
+	previousCertKey = "previous.crt"
+
+	defaultWebhookCertDir           = "/etc/webhook/certs"
+	defaultWebhookCertSecret        = "veverse-server-operator-webhook-tls"
+	defaultWebhookServiceName       = "veverse-server-operator-webhook"
+	defaultWebhookConfigurationName = "veverse-server-operator"
+)
+
+// webhookCertificate provides the serving certificate of the webhook server
+type webhookCertificate interface {
+	GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// newWebhookCertificate returns the certificate selected by WEBHOOK_CERT_MODE: "self-signed" generates a certificate
+// managed by the operator, "files" (default) reads the certificate from WEBHOOK_CERT_DIR, e.g. issued by cert-manager
+func newWebhookCertificate(ctx context.Context) (webhookCertificate, error) {
+	switch mode := getEnv("WEBHOOK_CERT_MODE", "files"); mode {
+	case "self-signed":
+		certificate, err := newSelfSignedCertificate(ctx, getEnv("WEBHOOK_CERT_SECRET", defaultWebhookCertSecret), getEnv("WEBHOOK_SERVICE_NAME", defaultWebhookServiceName), getEnv("WEBHOOK_CONFIGURATION_NAME", defaultWebhookConfigurationName))
+		if err != nil {
+			return nil, err
+		}
+		go certificate.Run(ctx)
+		return certificate, nil
+	case "files":
+		return newFileCertificate(getEnv("WEBHOOK_CERT_DIR", defaultWebhookCertDir))
+	default:
+		return nil, fmt.Errorf("unknown webhook certificate mode %s", mode)
+	}
+}
+
+// fileCertificate serves the key pair from files, which are reloaded when they change, e.g. a secret issued by
+// cert-manager mounted into the pod
+type fileCertificate struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func newFileCertificate(dir string) (*fileCertificate, error) {
+	c := &fileCertificate{
+		certFile: filepath.Join(dir, apiV1.TLSCertKey),
+		keyFile:  filepath.Join(dir, apiV1.TLSPrivateKeyKey),
+	}
+
+	// fail on start instead of on the first request if the certificate is missing
+	_, err := c.GetCertificate(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *fileCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.certFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %v", err)
+	}
+
+	if c.certificate != nil && info.ModTime().Equal(c.modTime) {
+		return c.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		// the key may not have been updated yet, keep serving the previous certificate
+		if c.certificate != nil {
+			Logger.Warningf("failed to reload webhook certificate: %v", err)
+			return c.certificate, nil
+		}
+		return nil, fmt.Errorf("failed to load certificate: %v", err)
+	}
+
+	Logger.Infof("loaded webhook certificate %s", c.certFile)
+
+	c.certificate = &certificate
+	c.modTime = info.ModTime()
+
+	return c.certificate, nil
+}
+
+// selfSignedCertificate generates a self-signed certificate for the webhook service and stores it in a secret shared by
+// all operator instances, the certificate is added as the CA bundle of the webhook configurations
+type selfSignedCertificate struct {
+	secretName    string
+	dnsNames      []string
+	configuration string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+}
+
+func newSelfSignedCertificate(ctx context.Context, secretName string, serviceName string, configuration string) (*selfSignedCertificate, error) {
+	namespace := ctx.Value("namespace").(string)
+
+	c := &selfSignedCertificate{
+		secretName: secretName,
+		dnsNames: []string{
+			serviceName,
+			fmt.Sprintf("%s.%s", serviceName, namespace),
+			fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", serviceName, namespace),
+		},
+		configuration: configuration,
+	}
+
+	err := c.reconcile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run renews the certificate before it expires and picks up certificates renewed by other instances
+func (c *selfSignedCertificate) Run(ctx context.Context) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := c.reconcile(ctx)
+		if err != nil {
+			Logger.Errorf("failed to reconcile webhook certificate: %v", err)
+		}
+	}, selfSignedCertificateCheckInterval)
+}
+
+func (c *selfSignedCertificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.certificate, nil
+}
+
+// reconcile loads the certificate from the secret, replaces it if it is missing or expires soon and updates the CA
+// bundle of the webhook configuration
+func (c *selfSignedCertificate) reconcile(ctx context.Context) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	secrets := clientset.CoreV1().Secrets(namespace)
+
+	var certPem, keyPem, previousCertPem []byte
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, c.secretName, metaV1.GetOptions{})
+		notFound := errors.IsNotFound(err)
+		if err != nil && !notFound {
+			return fmt.Errorf("failed to get certificate secret: %v", err)
+		}
+
+		if !notFound && isCertificateValid(secret.Data[apiV1.TLSCertKey], c.dnsNames) {
+			certPem, keyPem = secret.Data[apiV1.TLSCertKey], secret.Data[apiV1.TLSPrivateKeyKey]
+			previousCertPem = secret.Data[previousCertKey]
+			return nil
+		}
+
+		certPem, keyPem, err = generateSelfSignedCertificate(c.dnsNames)
+		if err != nil {
+			return err
+		}
+
+		if notFound {
+			Logger.Infof("creating webhook certificate secret %s", c.secretName)
+			_, err = secrets.Create(ctx, &apiV1.Secret{
+				ObjectMeta: metaV1.ObjectMeta{Name: c.secretName},
+				Type:       apiV1.SecretTypeTLS,
+				Data:       map[string][]byte{apiV1.TLSCertKey: certPem, apiV1.TLSPrivateKeyKey: keyPem},
+			}, metaV1.CreateOptions{})
+			// another instance has created the secret first, retry to use its certificate
+			if errors.IsAlreadyExists(err) {
+				return errors.NewConflict(apiV1.Resource("secrets"), c.secretName, err)
+			}
+			return err
+		}
+
+		Logger.Infof("renewing webhook certificate secret %s", c.secretName)
+		previousCertPem = secret.Data[apiV1.TLSCertKey]
+		secret = secret.DeepCopy()
+		secret.Data = map[string][]byte{apiV1.TLSCertKey: certPem, apiV1.TLSPrivateKeyKey: keyPem, previousCertKey: previousCertPem}
+		_, err = secrets.Update(ctx, secret, metaV1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store certificate secret: %v", err)
+	}
+
+	certificate, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %v", err)
+	}
+
+	c.mu.Lock()
+	c.certificate = &certificate
+	c.mu.Unlock()
+
+	// the other instances serve the previous certificate until their next check, every instance builds the same bundle
+	// from the secret, so the previous certificate is not removed by an instance which did not renew it
+	caBundle := certPem
+	if isCertificateUnexpired(previousCertPem) {
+		caBundle = append(append([]byte{}, certPem...), previousCertPem...)
+	}
+
+	return updateWebhookCaBundle(ctx, c.configuration, caBundle)
+}
+
+// updateWebhookCaBundle sets the CA bundle of all webhooks of the validating and the mutating webhook configuration,
+// the mutating webhook configuration is skipped if it does not exist
+func updateWebhookCaBundle(ctx context.Context, name string, caBundle []byte) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configuration, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, name, metaV1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get validating webhook configuration: %v", err)
+		}
+
+		changed := false
+		configuration = configuration.DeepCopy()
+		for i := range configuration.Webhooks {
+			if !bytes.Equal(configuration.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				configuration.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		Logger.Infof("updating CA bundle of validating webhook configuration %s", name)
+		_, err = clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Update(ctx, configuration, metaV1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configuration, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, name, metaV1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to get mutating webhook configuration: %v", err)
+		}
+
+		changed := false
+		configuration = configuration.DeepCopy()
+		for i := range configuration.Webhooks {
+			if !bytes.Equal(configuration.Webhooks[i].ClientConfig.CABundle, caBundle) {
+				configuration.Webhooks[i].ClientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+
+		if !changed {
+			return nil
+		}
+
+		Logger.Infof("updating CA bundle of mutating webhook configuration %s", name)
+		_, err = clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, configuration, metaV1.UpdateOptions{})
+		return err
+	})
+}
+
+// isCertificateValid checks that the PEM encoded certificate covers the DNS names and does not expire soon
+func isCertificateValid(certPem []byte, dnsNames []string) bool {
+	block, _ := pem.Decode(certPem)
+	if block == nil {
+		return false
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	if time.Until(certificate.NotAfter) < selfSignedCertificateRenewBefore {
+		return false
+	}
+
+	for _, dnsName := range dnsNames {
+		if certificate.VerifyHostname(dnsName) != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isCertificateUnexpired checks that the PEM encoded certificate has not expired yet
+func isCertificateUnexpired(certPem []byte) bool {
+	block, _ := pem.Decode(certPem)
+	if block == nil {
+		return false
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Before(certificate.NotAfter)
+}
+
+// generateSelfSignedCertificate returns a PEM encoded self-signed certificate for the DNS names and its key, the
+// certificate is its own CA
+func generateSelfSignedCertificate(dnsNames []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:              dnsNames,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %v", err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key: %v", err)
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	return certPem, keyPem, nil
+}
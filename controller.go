@@ -0,0 +1,592 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilRuntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreInformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sync/atomic"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	veverseInformers "veverse-server-operator/pkg/client/informers/externalversions/veverse/v1"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+const (
+	// reconcileBaseDelay is the initial delay before a failed game server is reconciled again, doubled on each failure
+	reconcileBaseDelay = 1 * time.Second
+	// reconcileMaxDelay caps the exponential backoff of failed reconciles
+	reconcileMaxDelay = 5 * time.Minute
+)
+
+// Controller converges game server resources, their deployments, services and database records.
+// Work items are game server names, which are the game server ids.
+type Controller struct {
+	queue            workqueue.RateLimitingInterface
+	gameServerLister veverseListers.GameServerLister
+	gameServerSynced cache.InformerSynced
+	podSynced        cache.InformerSynced
+	resyncInterval   time.Duration
+	startTimeout     time.Duration
+	// lastReconcileAt is the unix time in nanoseconds of the last successful reconcile pass, reported by the liveness endpoint
+	lastReconcileAt atomic.Int64
+}
+
+func NewController(gameServerInformer veverseInformers.GameServerInformer, podInformer coreInformers.PodInformer, resyncInterval time.Duration, startTimeout time.Duration) (*Controller, error) {
+	c := &Controller{
+		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(reconcileBaseDelay, reconcileMaxDelay), "gameservers"),
+		gameServerLister: gameServerInformer.Lister(),
+		gameServerSynced: gameServerInformer.Informer().HasSynced,
+		podSynced:        podInformer.Informer().HasSynced,
+		resyncInterval:   resyncInterval,
+		startTimeout:     startTimeout,
+	}
+
+	_, err := gameServerInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueGameServer,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueueGameServer(newObj)
+		},
+		DeleteFunc: c.enqueueGameServer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add event handler: %v", err)
+	}
+
+	// pod state changes drive the game server record status
+	_, err = podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueuePod,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueuePod(newObj)
+		},
+		DeleteFunc: c.enqueuePod,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add pod event handler: %v", err)
+	}
+
+	return c, nil
+}
+
+func (c *Controller) enqueueGameServer(obj interface{}) {
+	// handles deleted objects wrapped in a tombstone as well
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		Logger.Errorf("failed to get game server key: %v", err)
+		return
+	}
+
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		Logger.Errorf("failed to split game server key: %v", err)
+		return
+	}
+
+	c.queue.Add(name)
+}
+
+// enqueuePod adds the game server owning the pod to the queue, pods not belonging to a game server are ignored
+func (c *Controller) enqueuePod(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	pod, ok := obj.(*apiV1.Pod)
+	if !ok {
+		Logger.Errorf("failed to convert to pod: %v", obj)
+		return
+	}
+
+	id := getPodGameServerId(pod)
+	if id == uuid.Nil {
+		return
+	}
+
+	c.queue.Add(id.String())
+}
+
+// enqueueGameServerRecords adds all active game server records to the queue, so records without a matching game server
+// resource (e.g. deleted while the operator was down) are converged as well
+func (c *Controller) enqueueGameServerRecords(ctx context.Context) {
+	gameServerRecords, err := GetActiveGameServers(ctx)
+	if err != nil {
+		Logger.Errorf("failed to get active game servers: %v", err)
+		return
+	}
+
+	for _, gameServerRecord := range gameServerRecords.Entities {
+		c.queue.Add(gameServerRecord.Id.String())
+	}
+
+	// without game servers there is nothing to reconcile, which counts as a successful pass
+	if len(gameServerRecords.Entities) == 0 {
+		gameServers, err := c.gameServerLister.List(labels.Everything())
+		if err == nil && len(gameServers) == 0 {
+			c.markReconciled()
+		}
+	}
+}
+
+// markReconciled records a successful reconcile pass
+func (c *Controller) markReconciled() {
+	c.lastReconcileAt.Store(time.Now().UnixNano())
+}
+
+// LastReconcileAt returns the time of the last successful reconcile pass, zero if the controller has not started
+func (c *Controller) LastReconcileAt() time.Time {
+	lastReconcileAt := c.lastReconcileAt.Load()
+	if lastReconcileAt == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, lastReconcileAt)
+}
+
+// Run starts the workers and blocks until the context is cancelled
+func (c *Controller) Run(ctx context.Context, workers int) error {
+	defer utilRuntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	Logger.Infof("waiting for informer caches to sync")
+	if !cache.WaitForCacheSync(ctx.Done(), c.gameServerSynced, c.podSynced) {
+		return fmt.Errorf("failed to sync informer caches")
+	}
+
+	// the workers get the maximum reconcile age to complete the first pass
+	c.markReconciled()
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	// periodically pick up database records, game server resources requeue themselves after each reconcile
+	go wait.UntilWithContext(ctx, c.enqueueGameServerRecords, c.resyncInterval)
+
+	Logger.Infof("started %d workers", workers)
+	<-ctx.Done()
+	Logger.Infof("shutting down workers")
+
+	return nil
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	name, ok := item.(string)
+	if !ok {
+		c.queue.Forget(item)
+		Logger.Errorf("unexpected work item: %v", item)
+		return true
+	}
+
+	id, err := uuid.FromString(name)
+	if err != nil {
+		// retrying will not fix a malformed name
+		c.queue.Forget(item)
+		Logger.Errorf("failed to parse game server id %s: %v", name, err)
+		return true
+	}
+
+	start := time.Now()
+	requeueAfter, err := c.Reconcile(ctx, id)
+	observeReconcile("game_server", start, err)
+	if err != nil {
+		Logger.Errorf("failed to reconcile game server %s, retrying: %v", name, err)
+		c.queue.AddRateLimited(item)
+		return true
+	}
+
+	c.queue.Forget(item)
+	c.markReconciled()
+	if requeueAfter > 0 {
+		c.queue.AddAfter(item, requeueAfter)
+	}
+
+	return true
+}
+
+// Reconcile converges the game server resource, its deployment, service and database record. It is idempotent and
+// returns the delay after which the game server should be checked again, zero if no further checks are required.
+func (c *Controller) Reconcile(ctx context.Context, id uuid.UUID) (time.Duration, error) {
+	namespace := ctx.Value("namespace").(string)
+
+	gameServer, err := c.gameServerLister.GameServers(namespace).Get(id.String())
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return 0, fmt.Errorf("failed to get game server: %v", err)
+		}
+		gameServer = nil
+	}
+
+	gameServerRecord, err := GetGameServer(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	recordActive := gameServerRecord != nil && isGameServerActive(gameServerRecord.Status)
+
+	// game server resource has been removed without the finalizer (e.g. force deleted), release its deployment and service
+	// and mark the record offline, owned resources are normally garbage collected by the cluster
+	if gameServer == nil {
+		return 0, c.reconcileDeleted(ctx, id, nil, recordActive)
+	}
+
+	// game server resource is being deleted, let the players leave and mark the record offline before releasing the finalizer
+	if gameServer.DeletionTimestamp != nil {
+		if !hasGameServerFinalizer(gameServer) {
+			return 0, nil
+		}
+
+		if recordActive {
+			drained, drainStartedAt, err := reconcileGameServerDrain(ctx, id, gameServer)
+			if err != nil {
+				return 0, err
+			}
+
+			if !drained {
+				err = updateGameServerDrainStatus(ctx, gameServer, drainStartedAt)
+				if err != nil {
+					return 0, err
+				}
+				return drainCheckInterval, nil
+			}
+		}
+
+		err = c.reconcileDeleted(ctx, id, gameServer, recordActive)
+		if err != nil {
+			return 0, err
+		}
+
+		return 0, removeGameServerFinalizer(ctx, gameServer)
+	}
+
+	if !hasGameServerFinalizer(gameServer) {
+		gameServer, err = addGameServerFinalizer(ctx, gameServer)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// game server has finished or failed, delete the resource, the deployment and service are garbage collected with it
+	if gameServerRecord != nil && (gameServerRecord.Status == GameServerStatusOffline || gameServerRecord.Status == GameServerStatusError) {
+		Logger.Infof("game server %s is %s, deleting game server resource", id, gameServerRecord.Status)
+
+		// the host port is free once the game server has exited, even if its pod is still terminating
+		err = hostPorts.Release(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		err = deleteGameServerClusterResource(ctx, id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete game server: %v", err)
+		}
+		return 0, nil
+	}
+
+	// inline api credentials are moved to a secret before the deployment references them
+	gameServer, err = migrateGameServerApiCredentials(ctx, id, gameServer)
+	if err != nil {
+		return 0, err
+	}
+
+	// the token secret has to exist before the deployment mounts it
+	if gameServerTokens != nil {
+		err = reconcileGameServerToken(ctx, id, gameServer)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// the host port has to be allocated before the deployment binds it
+	gameServer, err = reconcileGameServerHostPort(ctx, id, gameServer)
+	if err != nil {
+		return 0, err
+	}
+
+	portPending, reconcileErr := c.reconcileResources(ctx, id, gameServer)
+
+	// the record goes online once players can connect, so the lifecycle waits for the port
+	if reconcileErr == nil && portPending == 0 {
+		var recordStatus string
+		recordStatus, reconcileErr = reconcileGameServerLifecycle(ctx, id, gameServer, gameServerRecord, c.startTimeout)
+		if recordStatus != "" {
+			gameServerRecord.Status = recordStatus
+		}
+	}
+
+	// status reflects failed reconciles as well, so it is updated regardless of the reconcile result
+	err = updateGameServerStatus(ctx, id, gameServer, gameServerRecord, reconcileErr)
+	if reconcileErr != nil {
+		return 0, reconcileErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// game servers which have just failed or exited are torn down on the next pass
+	if gameServerRecord != nil && !isGameServerActive(gameServerRecord.Status) {
+		return time.Second, nil
+	}
+
+	if portPending > 0 {
+		return portPending, nil
+	}
+
+	return c.resyncInterval, nil
+}
+
+// reconcileResources creates the deployment and service of the game server if they are missing, returns the delay after
+// which the port should be checked again, zero if it has been assigned
+func (c *Controller) reconcileResources(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) (time.Duration, error) {
+	// deployments and services are named after the spec id, so it has to match the resource name
+	specId, err := getGameServerId(gameServer)
+	if err != nil {
+		return 0, err
+	}
+	if specId != id {
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonSpecInvalid, "spec id %s does not match the resource name", specId)
+		return 0, fmt.Errorf("game server spec id %s does not match the resource name %s", specId, id)
+	}
+
+	err = c.reconcileDeployment(ctx, id, gameServer)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.reconcileService(ctx, id, gameServer)
+}
+
+// reconcileDeleted releases the deployment and service of the game server and marks its record offline, the game server
+// resource is nil if it has already been removed
+func (c *Controller) reconcileDeleted(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, recordActive bool) error {
+	err := deleteGameServerDeploymentClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = deleteGameServerServiceClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = hostPorts.Release(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// the game server must not access the API anymore, even if its pod is still terminating
+	if gameServerTokens != nil {
+		err = revokeGameServerTokens(ctx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	if recordActive {
+		Logger.Warningf("game server resource deleted for game server: %v, marking server as offline", id)
+
+		err = SetGameServerOffline(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to set game server offline: %v", err)
+		}
+
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonMarkedOffline, "game server resource deleted, marked game server offline")
+	}
+
+	return nil
+}
+
+func (c *Controller) reconcileDeployment(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) error {
+	desired, err := buildGameServerDeployment(gameServer)
+	if err != nil {
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonSpecInvalid, "%v", err)
+		return err
+	}
+
+	deployment, err := getGameServerDeploymentClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if deployment == nil {
+		Logger.Infof("creating deployment for game server %s", id)
+		err = createGameServerDeploymentClusterResource(ctx, desired)
+		if err != nil {
+			recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonDeploymentCreateFailed, "%v", err)
+			return err
+		}
+
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonDeploymentCreated, "created deployment %s", desired.Name)
+
+		notify(ctx, Notification{
+			Event:        NotificationGameServerCreated,
+			Title:        "Game server created",
+			Message:      fmt.Sprintf("Game server %s is starting.", gameServer.Name),
+			GameServerId: id,
+			Fields:       gameServerNotificationFields(gameServer),
+		})
+
+		return nil
+	}
+
+	// adopt deployments created before the owner references, labels and spec hashes were introduced
+	if metaV1.GetControllerOf(deployment) == nil || deployment.Labels[LabelManagedBy] != managedByOperator || deployment.Annotations[AnnotationSpecHash] == "" {
+		Logger.Infof("adopting deployment for game server %s", id)
+		return adoptGameServerDeploymentClusterResource(ctx, deployment, gameServer, desired)
+	}
+
+	if getDeploymentTemplateHash(deployment) == desired.Annotations[AnnotationTemplateHash] {
+		return nil
+	}
+
+	// updates restart the game server, so they may have to wait until the game server is empty
+	deferred, err := isGameServerUpdateDeferred(ctx, id, gameServer, isDeploymentSpecChanged(deployment, desired))
+	if err != nil {
+		return err
+	}
+	if deferred {
+		Logger.Infof("deferring deployment update for game server %s until no players are connected", id)
+		return nil
+	}
+
+	Logger.Infof("updating deployment for game server %s", id)
+	return updateGameServerDeploymentClusterResource(ctx, deployment, desired)
+}
+
+// isGameServerUpdateDeferred checks if the game server can not be restarted right now, spec changes follow the update
+// policy of the game server, changes of the operator settings always wait until no players are connected
+func isGameServerUpdateDeferred(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, specChanged bool) (bool, error) {
+	if specChanged && gameServer.Spec.UpdatePolicy != veverseV1.UpdatePolicyWhenEmpty {
+		return false, nil
+	}
+
+	players, err := GetGameServerPlayerCount(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	return players > 0, nil
+}
+
+// reconcileService creates, updates or deletes the service of the game server as required by its exposure and writes
+// the endpoint players connect to to the game server record
+func (c *Controller) reconcileService(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) (time.Duration, error) {
+	exposure, err := getGameServerExposure(gameServer)
+	if err != nil {
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonSpecInvalid, "%v", err)
+		return 0, err
+	}
+
+	service, err := getGameServerServiceClusterResource(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	desired := exposure.buildService(id, gameServer)
+	if desired == nil {
+		// the exposure has been changed to one without a service
+		if service != nil {
+			Logger.Infof("deleting service for game server %s", id)
+			err = deleteGameServerServiceClusterResource(ctx, id)
+			if err != nil {
+				return 0, err
+			}
+			service = nil
+		}
+	} else if service == nil {
+		Logger.Infof("creating service for game server %s", id)
+		service, err = createGameServerServiceClusterResource(ctx, desired)
+		if err != nil {
+			recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonServiceCreateFailed, "%v", err)
+			return 0, err
+		}
+
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonServiceCreated, "created service %s", getResourceName(id))
+	} else {
+		// adopt services created before the owner references and labels were introduced
+		if metaV1.GetControllerOf(service) == nil || service.Labels[LabelManagedBy] != managedByOperator {
+			Logger.Infof("adopting service for game server %s", id)
+			service, err = adoptGameServerServiceClusterResource(ctx, service, gameServer)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if !isGameServerServiceExposed(service, desired) {
+			Logger.Infof("updating service type of game server %s to %s", id, desired.Spec.Type)
+			service, err = updateGameServerServiceClusterResource(ctx, service, desired)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	host, endpointPorts, err := exposure.getEndpoint(ctx, id, gameServer, service)
+	if err != nil {
+		return 0, err
+	}
+
+	// pods are scheduled and load balancers are provisioned asynchronously, the port is checked again shortly
+	port := endpointPorts[gameServerPortName]
+	if port == 0 {
+		return portPendingCheckInterval, nil
+	}
+
+	// update the game server record with the endpoint, does nothing if the endpoint is already set
+	err = SetGameServerEndpoint(ctx, id, host, port)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set game server endpoint: %v", err)
+	}
+
+	// the other ports are stored with the record, so clients can discover them
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	var portRecords []gameServerPortRecord
+	for _, portStatus := range buildGameServerPortStatuses(gameServer, service, pod, host, endpointPorts) {
+		portRecords = append(portRecords, gameServerPortRecord{
+			Name:          portStatus.Name,
+			Protocol:      string(portStatus.Protocol),
+			ContainerPort: portStatus.ContainerPort,
+			Policy:        string(portStatus.Policy),
+			Host:          portStatus.Host,
+			Port:          portStatus.Port,
+		})
+	}
+
+	err = SetGameServerPorts(ctx, id, portRecords)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set game server ports: %v", err)
+	}
+
+	// the status reports the port once the reconcile has finished, so the event is recorded once per port
+	if gameServer.Status.Port != port {
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonPortAssigned, "%s %d assigned", exposure.describePort(), port)
+	}
+
+	return 0, nil
+}
+
+func isGameServerActive(status string) bool {
+	return status == GameServerStatusOnline || status == GameServerStatusStarting || status == GameServerStatusCreated
+}
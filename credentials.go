@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+)
+
+// keys of the API credentials secrets
+const (
+	apiCredentialsKeyV1Key      = "apiV1Key"
+	apiCredentialsKeyV2Email    = "apiV2Email"
+	apiCredentialsKeyV2Password = "apiV2Password"
+)
+
+// ApiCredentialsMigration selects how the operator moves API credentials stored in plaintext in game server and fleet
+// specs to secrets
+type ApiCredentialsMigration string
+
+const (
+	// ApiCredentialsMigrationNone keeps inline credentials, they are passed to the game server as literal values
+	ApiCredentialsMigrationNone ApiCredentialsMigration = "none"
+	// ApiCredentialsMigrationPerServer moves inline credentials to a secret owned by the game server or fleet
+	ApiCredentialsMigrationPerServer ApiCredentialsMigration = "per-server"
+	// ApiCredentialsMigrationShared moves inline credentials to a secret shared by all game servers and fleets with the
+	// same credentials, which is garbage collected with the last of them
+	ApiCredentialsMigrationShared ApiCredentialsMigration = "shared"
+)
+
+const (
+	// apiCredentialsSecretSuffix is appended to the game server resource name or the fleet name to name its secret
+	apiCredentialsSecretSuffix = "-api-credentials"
+	// sharedApiCredentialsSecretPrefix is the name prefix of shared secrets, followed by the hash of the credentials
+	sharedApiCredentialsSecretPrefix = "gameserver-api-credentials-"
+)
+
+// defaultApiCredentialsSecret is the namespace wide secret used by game servers without a credentials secret
+var defaultApiCredentialsSecret string
+
+// apiCredentialsMigration is the migration of inline credentials, set from the env
+var apiCredentialsMigration = ApiCredentialsMigrationNone
+
+// parseApiCredentialsMigration parses the migration mode, none if empty
+func parseApiCredentialsMigration(value string) (ApiCredentialsMigration, error) {
+	switch migration := ApiCredentialsMigration(value); migration {
+	case "":
+		return ApiCredentialsMigrationNone, nil
+	case ApiCredentialsMigrationNone, ApiCredentialsMigrationPerServer, ApiCredentialsMigrationShared:
+		return migration, nil
+	default:
+		return "", fmt.Errorf("unknown api credentials migration %s", value)
+	}
+}
+
+// hasInlineApiCredentials checks if the API key or password are stored in the spec
+func hasInlineApiCredentials(api *veverseV1.ApiSettings) bool {
+	return api.V1.Key != "" || api.V2.Password != ""
+}
+
+// getApiCredentialsSecretName returns the secret the API credentials are read from, empty if there is none
+func getApiCredentialsSecretName(api *veverseV1.ApiSettings) string {
+	if api.CredentialsSecret != "" {
+		return api.CredentialsSecret
+	}
+
+	return defaultApiCredentialsSecret
+}
+
+// buildApiCredentialEnv returns the env variable of an API credential, inline values which have not been migrated yet
+// are passed as literal values, the others reference the credentials secret
+func buildApiCredentialEnv(name string, value string, secretName string, key string) apiV1.EnvVar {
+	if value != "" || secretName == "" {
+		return apiV1.EnvVar{Name: name, Value: value}
+	}
+
+	// the secret may hold only some of the credentials, e.g. only the v2 account
+	optional := true
+	return apiV1.EnvVar{
+		Name: name,
+		ValueFrom: &apiV1.EnvVarSource{
+			SecretKeyRef: &apiV1.SecretKeySelector{
+				LocalObjectReference: apiV1.LocalObjectReference{Name: secretName},
+				Key:                  key,
+				Optional:             &optional,
+			},
+		},
+	}
+}
+
+// getInlineApiCredentialsData returns the secret data of the inline credentials
+func getInlineApiCredentialsData(api *veverseV1.ApiSettings) map[string][]byte {
+	data := map[string][]byte{}
+	if api.V1.Key != "" {
+		data[apiCredentialsKeyV1Key] = []byte(api.V1.Key)
+	}
+	if api.V2.Password != "" {
+		data[apiCredentialsKeyV2Password] = []byte(api.V2.Password)
+	}
+
+	return data
+}
+
+// getSharedApiCredentialsSecretName names the shared secret after the hash of the credentials, so game servers with
+// the same credentials share the secret
+func getSharedApiCredentialsSecretName(api *veverseV1.ApiSettings) string {
+	hash := sha256.Sum256([]byte(api.V1.Key + "\x00" + api.V2.Password))
+	return sharedApiCredentialsSecretPrefix + hex.EncodeToString(hash[:8])
+}
+
+// migrateGameServerApiCredentials moves the inline API credentials of the game server to a secret and replaces them
+// with a reference to the secret, returns the updated game server. Credentials of fleet game servers are migrated with
+// the fleet template, otherwise the fleet would restore them.
+func migrateGameServerApiCredentials(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) (*veverseV1.GameServer, error) {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	api := &gameServer.Spec.Settings.Api
+	if apiCredentialsMigration == ApiCredentialsMigrationNone || !hasInlineApiCredentials(api) || gameServer.Labels[LabelFleet] != "" {
+		return gameServer, nil
+	}
+
+	secretName, err := storeInlineApiCredentials(ctx, api, getResourceName(id)+apiCredentialsSecretSuffix, getGameServerOwnerReference(gameServer))
+	if err != nil {
+		return nil, err
+	}
+
+	Logger.Infof("moved api credentials of game server %s to secret %s", id, secretName)
+
+	gameServer = gameServer.DeepCopy()
+	clearInlineApiCredentials(&gameServer.Spec.Settings.Api, secretName)
+
+	gameServer, err = veverseClientset.VeverseV1().GameServers(namespace).Update(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update game server api credentials: %v", err)
+	}
+
+	recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonCredentialsMigrated, "moved api credentials to secret %s", secretName)
+
+	return gameServer, nil
+}
+
+// migrateFleetApiCredentials moves the inline API credentials of the fleet template to a secret and replaces them with
+// a reference to the secret, the fleet game servers get the reference with the next template update. Returns true if
+// the fleet has been updated.
+func migrateFleetApiCredentials(ctx context.Context, fleet *veverseV1.GameServerFleet) (bool, error) {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	api := &fleet.Spec.Template.Settings.Api
+	if apiCredentialsMigration == ApiCredentialsMigrationNone || !hasInlineApiCredentials(api) {
+		return false, nil
+	}
+
+	owner := *metaV1.NewControllerRef(fleet, veverseV1.SchemeGroupVersion.WithKind("GameServerFleet"))
+	secretName, err := storeInlineApiCredentials(ctx, api, fleet.Name+apiCredentialsSecretSuffix, owner)
+	if err != nil {
+		return false, err
+	}
+
+	Logger.Infof("moved api credentials of fleet %s to secret %s", fleet.Name, secretName)
+
+	fleet = fleet.DeepCopy()
+	clearInlineApiCredentials(&fleet.Spec.Template.Settings.Api, secretName)
+
+	_, err = veverseClientset.VeverseV1().GameServerFleets(namespace).Update(ctx, fleet, metaV1.UpdateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to update fleet api credentials: %v", err)
+	}
+
+	return true, nil
+}
+
+// storeInlineApiCredentials writes the inline credentials to the secret selected by the migration mode, the secret
+// owned by the owner or the shared secret of the credentials, returns the secret name
+func storeInlineApiCredentials(ctx context.Context, api *veverseV1.ApiSettings, ownedSecretName string, owner metaV1.OwnerReference) (string, error) {
+	secretName := ownedSecretName
+	if apiCredentialsMigration == ApiCredentialsMigrationShared {
+		secretName = getSharedApiCredentialsSecretName(api)
+		// shared secrets have several owners, none of them is the controller
+		owner.Controller = nil
+		owner.BlockOwnerDeletion = nil
+	}
+
+	err := applyApiCredentialsSecret(ctx, secretName, getInlineApiCredentialsData(api), owner)
+	if err != nil {
+		return "", err
+	}
+
+	return secretName, nil
+}
+
+// clearInlineApiCredentials removes the inline credentials and references the secret they have been moved to, the
+// email is kept, as it overrides the email of the secret
+func clearInlineApiCredentials(api *veverseV1.ApiSettings, secretName string) {
+	api.V1.Key = ""
+	api.V2.Password = ""
+	api.CredentialsSecret = secretName
+}
+
+// applyApiCredentialsSecret creates the secret or updates its data and adds the owner reference, so it is garbage
+// collected once all of its owners have been deleted
+func applyApiCredentialsSecret(ctx context.Context, name string, data map[string][]byte, owner metaV1.OwnerReference) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	secrets := clientset.CoreV1().Secrets(namespace)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, name, metaV1.GetOptions{})
+		if errors.IsNotFound(err) {
+			_, err = secrets.Create(ctx, &apiV1.Secret{
+				ObjectMeta: metaV1.ObjectMeta{
+					Name:            name,
+					Labels:          map[string]string{LabelManagedBy: managedByOperator},
+					OwnerReferences: []metaV1.OwnerReference{owner},
+				},
+				Type: apiV1.SecretTypeOpaque,
+				Data: data,
+			}, metaV1.CreateOptions{})
+			// another game server has created the shared secret first, retry to add the owner reference
+			if errors.IsAlreadyExists(err) {
+				return errors.NewConflict(apiV1.Resource("secrets"), name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if isApiCredentialsSecretApplied(secret, data, owner.UID) {
+			return nil
+		}
+
+		secret = secret.DeepCopy()
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		for key, value := range data {
+			secret.Data[key] = value
+		}
+		if !hasOwnerReference(secret.OwnerReferences, owner.UID) {
+			secret.OwnerReferences = append(secret.OwnerReferences, owner)
+		}
+
+		_, err = secrets.Update(ctx, secret, metaV1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store api credentials secret %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// isApiCredentialsSecretApplied checks if the secret contains the data and is owned by the owner
+func isApiCredentialsSecretApplied(secret *apiV1.Secret, data map[string][]byte, owner types.UID) bool {
+	for key, value := range data {
+		if !bytes.Equal(secret.Data[key], value) {
+			return false
+		}
+	}
+
+	return hasOwnerReference(secret.OwnerReferences, owner)
+}
+
+func hasOwnerReference(ownerReferences []metaV1.OwnerReference, uid types.UID) bool {
+	for _, ownerReference := range ownerReferences {
+		if ownerReference.UID == uid {
+			return true
+		}
+	}
+
+	return false
+}
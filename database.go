@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	vModel "dev.hackerman.me/artheon/veverse-shared/model"
+	"errors"
 	"fmt"
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgtype"
@@ -12,6 +13,7 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/sirupsen/logrus"
 	"os"
+	"time"
 )
 
 func DatabaseOpen(ctx context.Context) (context.Context, error) {
@@ -79,6 +81,7 @@ func GetApps(ctx context.Context) ([]vModel.AppV2, error) {
 	if !ok {
 		return apps, fmt.Errorf("unable to get database connection")
 	}
+	defer observeDatabaseQuery("get_apps", time.Now())
 
 	rows, err := db.Query(ctx, "SELECT id, name, external FROM apps")
 	if err != nil {
@@ -105,6 +108,7 @@ func GetReleases(ctx context.Context) ([]vModel.ReleaseV2, error) {
 	if !ok {
 		return releases, fmt.Errorf("unable to get database connection")
 	}
+	defer observeDatabaseQuery("get_releases", time.Now())
 
 	rows, err := db.Query(ctx, "select e.id, e.created_at, e.updated_at, r.entity_id, r.name, r.version from release_v2 r left join entities e on r.id = e.id")
 	if err != nil {
@@ -130,6 +134,57 @@ func GetReleases(ctx context.Context) ([]vModel.ReleaseV2, error) {
 	return releases, nil
 }
 
+// gameServerRelease is the release a game server is started with
+type gameServerRelease struct {
+	Id      uuid.UUID
+	Version string
+}
+
+// GetLatestRelease returns the most recently created release of the app, or nil if the app has no releases
+func GetLatestRelease(ctx context.Context, appId uuid.UUID) (*gameServerRelease, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_latest_release", time.Now())
+
+	var release gameServerRelease
+	err := db.QueryRow(ctx, `select r.id, coalesce(r.version, '')
+from release_v2 r
+left join entities e on r.id = e.id
+where r.entity_id = $1
+order by e.created_at desc
+limit 1`, appId).Scan(&release.Id, &release.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get latest release: %v", err)
+	}
+
+	return &release, nil
+}
+
+// GetRelease returns the release with the given id, or nil if there is no such release
+func GetRelease(ctx context.Context, id uuid.UUID) (*gameServerRelease, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_release", time.Now())
+
+	var release gameServerRelease
+	err := db.QueryRow(ctx, `select r.id, coalesce(r.version, '') from release_v2 r where r.id = $1`, id).Scan(&release.Id, &release.Version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get release: %v", err)
+	}
+
+	return &release, nil
+}
+
 func GetOnlineGameServers(ctx context.Context) (vModel.GameServerV2Batch, error) {
 	var servers vModel.GameServerV2Batch
 
@@ -137,6 +192,7 @@ func GetOnlineGameServers(ctx context.Context) (vModel.GameServerV2Batch, error)
 	if !ok {
 		return servers, fmt.Errorf("unable to get database connection")
 	}
+	defer observeDatabaseQuery("get_online_game_servers", time.Now())
 
 	// query for the total number of online game servers that have been updated in the last minute (did not time out)
 	row := db.QueryRow(ctx, `select count(*) from game_server_v2 s left join entities e on s.id = e.id where s.status = 'online' and e.updated_at >= now() - interval '1 minute'`)
@@ -146,20 +202,7 @@ func GetOnlineGameServers(ctx context.Context) (vModel.GameServerV2Batch, error)
 	}
 
 	// query for all online game servers that have been updated in the last minute (did not time out)
-	rows, err := db.Query(ctx, `select e.id, 
-       e.created_at, 
-       e.updated_at, 
-       e.public, 
-       s.release_id,
-       s.world_id,
-       s.game_mode_id,
-       s.region_id,
-       s.type,
-       s.host,
-       s.port,
-       s.max_players,
-       s.status,
-       s.status_message
+	rows, err := db.Query(ctx, `select `+gameServerColumns+`
 from game_server_v2 s
 left join entities e on s.id = e.id
 where status = 'online' and e.updated_at >= now() - interval '1 minute'`)
@@ -169,21 +212,39 @@ where status = 'online' and e.updated_at >= now() - interval '1 minute'`)
 
 	for rows.Next() {
 		var server vModel.GameServerV2
-		err := rows.Scan(
-			&server.Id,
-			&server.CreatedAt,
-			&server.UpdatedAt,
-			&server.Public,
-			&server.ReleaseId,
-			&server.WorldId,
-			&server.GameModeId,
-			&server.RegionId,
-			&server.Type,
-			&server.Host,
-			&server.Port,
-			&server.MaxPlayers,
-			&server.Status,
-			&server.StatusMessage)
+		err := scanGameServer(rows, &server)
+		if err != nil {
+			return servers, err
+		}
+
+		servers.Entities = append(servers.Entities, server)
+	}
+
+	return servers, nil
+}
+
+// GetActiveGameServers returns all game servers that are expected to have a running deployment (created, starting or online)
+func GetActiveGameServers(ctx context.Context) (vModel.GameServerV2Batch, error) {
+	var servers vModel.GameServerV2Batch
+
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return servers, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_active_game_servers", time.Now())
+
+	rows, err := db.Query(ctx, `select `+gameServerColumns+`
+from game_server_v2 s
+left join entities e on s.id = e.id
+where s.status in ('created', 'starting', 'online')`)
+	if err != nil {
+		return servers, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var server vModel.GameServerV2
+		err := scanGameServer(rows, &server)
 		if err != nil {
 			return servers, err
 		}
@@ -191,14 +252,100 @@ where status = 'online' and e.updated_at >= now() - interval '1 minute'`)
 		servers.Entities = append(servers.Entities, server)
 	}
 
+	if err := rows.Err(); err != nil {
+		return servers, err
+	}
+
+	servers.Total = int64(len(servers.Entities))
+
 	return servers, nil
 }
 
+// GetGameServer returns the game server record with the given id, or nil if there is no such record
+func GetGameServer(ctx context.Context, id uuid.UUID) (*vModel.GameServerV2, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_game_server", time.Now())
+
+	row := db.QueryRow(ctx, `select `+gameServerColumns+`
+from game_server_v2 s
+left join entities e on s.id = e.id
+where s.id = $1`, id)
+
+	var server vModel.GameServerV2
+	err := scanGameServer(row, &server)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get game server: %v", err)
+	}
+
+	return &server, nil
+}
+
+// GetGameServerPlayerCount returns the number of players currently connected to the game server, reported by the game server binary
+func GetGameServerPlayerCount(ctx context.Context, id uuid.UUID) (int32, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return 0, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_game_server_player_count", time.Now())
+
+	var players int32
+	err := db.QueryRow(ctx, `select coalesce(s.players, 0) from game_server_v2 s where s.id = $1`, id).Scan(&players)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("unable to get game server player count: %v", err)
+	}
+
+	return players, nil
+}
+
+// gameServerColumns are the columns scanned by scanGameServer
+const gameServerColumns = `e.id,
+       e.created_at,
+       e.updated_at,
+       e.public,
+       s.release_id,
+       s.world_id,
+       s.game_mode_id,
+       s.region_id,
+       s.type,
+       s.host,
+       s.port,
+       s.max_players,
+       s.status,
+       s.status_message`
+
+func scanGameServer(row pgx.Row, server *vModel.GameServerV2) error {
+	return row.Scan(
+		&server.Id,
+		&server.CreatedAt,
+		&server.UpdatedAt,
+		&server.Public,
+		&server.ReleaseId,
+		&server.WorldId,
+		&server.GameModeId,
+		&server.RegionId,
+		&server.Type,
+		&server.Host,
+		&server.Port,
+		&server.MaxPlayers,
+		&server.Status,
+		&server.StatusMessage)
+}
+
 func SetGameServerOffline(ctx context.Context, id uuid.UUID) error {
 	db, ok := ctx.Value("database").(*pgxpool.Pool)
 	if !ok {
 		return fmt.Errorf("unable to get database connection")
 	}
+	defer observeDatabaseQuery("set_game_server_offline", time.Now())
 
 	_, err := db.Exec(ctx, `update game_server_v2 set status = 'offline' where id = $1`, id)
 	if err != nil {
@@ -213,15 +360,21 @@ func SetGameServerOffline(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-func SetGameServerPort(ctx context.Context, id uuid.UUID, port int32) error {
+// SetGameServerStatus sets the game server status and status message, does nothing if they are already set
+func SetGameServerStatus(ctx context.Context, id uuid.UUID, status string, message string) error {
 	db, ok := ctx.Value("database").(*pgxpool.Pool)
 	if !ok {
 		return fmt.Errorf("unable to get database connection")
 	}
+	defer observeDatabaseQuery("set_game_server_status", time.Now())
 
-	_, err := db.Exec(ctx, `update game_server_v2 set port = $1 where id = $2`, port, id)
+	tag, err := db.Exec(ctx, `update game_server_v2 set status = $1, status_message = $2 where id = $3 and (status is distinct from $1 or status_message is distinct from $2)`, status, message, id)
 	if err != nil {
-		return fmt.Errorf("unable to set server port: %v", err)
+		return fmt.Errorf("unable to set server status: %v", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil
 	}
 
 	_, err = db.Exec(ctx, `update entities set updated_at = now() where id = $1`, id)
@@ -231,3 +384,525 @@ func SetGameServerPort(ctx context.Context, id uuid.UUID, port int32) error {
 
 	return nil
 }
+
+// SetGameServerEndpoint sets the host and port players connect to, an empty host keeps the host of the record
+func SetGameServerEndpoint(ctx context.Context, id uuid.UUID, host string, port int32) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("set_game_server_endpoint", time.Now())
+
+	// skip the update if the endpoint is already set, so repeated reconciles do not bump the entity updated_at
+	tag, err := db.Exec(ctx, `update game_server_v2 set host = coalesce(nullif($1, ''), host), port = $2 where id = $3 and (port is distinct from $2 or ($1 <> '' and host is distinct from $1))`, host, port, id)
+	if err != nil {
+		return fmt.Errorf("unable to set server endpoint: %v", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	_, err = db.Exec(ctx, `update entities set updated_at = now() where id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("unable to update entity updated_at: %v", err)
+	}
+
+	return nil
+}
+
+// CreateGameServer inserts the entity and the game server record, the record starts in the created status
+func CreateGameServer(ctx context.Context, server *vModel.GameServerV2) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("create_game_server", time.Now())
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `insert into entities (id, entity_type, public) values ($1, 'game_server', $2)`, server.Id, server.Public)
+	if err != nil {
+		return fmt.Errorf("unable to create game server entity: %v", err)
+	}
+
+	_, err = tx.Exec(ctx, `insert into game_server_v2 (id, release_id, world_id, type, host, port, max_players, status) values ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		server.Id,
+		server.ReleaseId,
+		server.WorldId,
+		server.Type,
+		server.Host,
+		server.Port,
+		server.MaxPlayers,
+		GameServerStatusCreated)
+	if err != nil {
+		return fmt.Errorf("unable to create game server: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to commit game server: %v", err)
+	}
+
+	return nil
+}
+
+// GetGameServerOccupancy returns the number of connected players and the player slots of the active game servers with the given ids
+func GetGameServerOccupancy(ctx context.Context, ids []uuid.UUID) (players int64, capacity int64, err error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return 0, 0, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_game_server_occupancy", time.Now())
+
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	// uuid arrays are passed as text, the registered uuid type only handles single values
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	err = db.QueryRow(ctx, `select coalesce(sum(s.players), 0), coalesce(sum(s.max_players), 0)
+from game_server_v2 s
+where s.id = any($1::uuid[]) and s.status in ('created', 'starting', 'online')`, idStrings).Scan(&players, &capacity)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to get game server occupancy: %v", err)
+	}
+
+	return players, capacity, nil
+}
+
+// InsertFleetAutoscalerDecision appends the decision to the fleet autoscaler decision log
+func InsertFleetAutoscalerDecision(ctx context.Context, decision *FleetAutoscalerDecision) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("insert_fleet_autoscaler_decision", time.Now())
+
+	var schedule *string
+	if decision.Schedule != "" {
+		schedule = &decision.Schedule
+	}
+
+	_, err := db.Exec(ctx, `insert into fleet_autoscaler_decisions (namespace, autoscaler, fleet, current_replicas, desired_replicas, players, capacity, schedule, applied, reason) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		decision.Namespace,
+		decision.Autoscaler,
+		decision.Fleet,
+		decision.CurrentReplicas,
+		decision.DesiredReplicas,
+		decision.Players,
+		decision.Capacity,
+		schedule,
+		decision.Applied,
+		decision.Reason)
+	if err != nil {
+		return fmt.Errorf("unable to insert fleet autoscaler decision: %v", err)
+	}
+
+	return nil
+}
+
+// allocationCandidate is an online game server with free player slots
+type allocationCandidate struct {
+	Id   uuid.UUID
+	Free int32
+}
+
+// reservedPlayersColumn is the number of player slots reserved by allocations which have not expired yet, $1 is the
+// allocation ttl in seconds
+const reservedPlayersColumn = `(case when s.allocated_at >= now() - make_interval(secs => $1) then s.allocated_players else 0 end)`
+
+// GetAllocationCandidates returns online game servers matching the request with enough free player slots, fullest first,
+// so players are packed onto few game servers
+func GetAllocationCandidates(ctx context.Context, request *AllocationRequest, ttl time.Duration) ([]allocationCandidate, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_allocation_candidates", time.Now())
+
+	rows, err := db.Query(ctx, `select s.id, s.max_players - coalesce(s.players, 0) - `+reservedPlayersColumn+` as free
+from game_server_v2 s
+left join entities e on s.id = e.id
+where s.status = 'online'
+  and s.drain_started_at is null
+  and e.updated_at >= now() - interval '1 minute'
+  and ($2::uuid is null or s.world_id = $2)
+  and ($3::uuid is null or s.release_id = $3)
+  and ($4::uuid is null or s.region_id = $4)
+  and ($5::uuid is null or s.game_mode_id = $5)
+  and s.max_players - coalesce(s.players, 0) - `+reservedPlayersColumn+` >= $6
+order by free, s.id
+limit 100`, ttl.Seconds(), request.WorldId, request.ReleaseId, request.RegionId, request.GameModeId, request.Players)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get allocation candidates: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []allocationCandidate
+	for rows.Next() {
+		var candidate allocationCandidate
+		err := rows.Scan(&candidate.Id, &candidate.Free)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return candidates, nil
+}
+
+// AllocateGameServer reserves player slots on the game server if it is still online and has enough free slots, returns
+// the game server record or nil if the game server can not take the players anymore
+func AllocateGameServer(ctx context.Context, id uuid.UUID, players int32, ttl time.Duration) (*vModel.GameServerV2, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("allocate_game_server", time.Now())
+
+	// the row lock taken by the update makes the capacity check and the reservation atomic
+	tag, err := db.Exec(ctx, `update game_server_v2 s
+set allocated_players = `+reservedPlayersColumn+` + $3,
+    allocated_at      = now()
+where s.id = $2
+  and s.status = 'online'
+  and s.drain_started_at is null
+  and s.max_players - coalesce(s.players, 0) - `+reservedPlayersColumn+` >= $3`, ttl.Seconds(), id, players)
+	if err != nil {
+		return nil, fmt.Errorf("unable to allocate game server: %v", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	return GetGameServer(ctx, id)
+}
+
+// StartGameServerDrain marks the game server as draining, which removes it from allocation, returns the time the drain
+// started, which is kept if the game server is already draining
+func StartGameServerDrain(ctx context.Context, id uuid.UUID) (time.Time, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("start_game_server_drain", time.Now())
+
+	var drainStartedAt time.Time
+	err := db.QueryRow(ctx, `update game_server_v2 set drain_started_at = coalesce(drain_started_at, now()) where id = $1 returning drain_started_at`, id).Scan(&drainStartedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to start game server drain: %v", err)
+	}
+
+	return drainStartedAt, nil
+}
+
+// GetGameServerStatusCounts returns the number of game server records by status
+func GetGameServerStatusCounts(ctx context.Context) (map[string]int64, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_game_server_status_counts", time.Now())
+
+	rows, err := db.Query(ctx, `select coalesce(s.status, ''), count(*) from game_server_v2 s group by s.status`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get game server status counts: %v", err)
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var (
+			status string
+			count  int64
+		)
+		err = rows.Scan(&status, &count)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan game server status count: %v", err)
+		}
+		counts[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to get game server status counts: %v", err)
+	}
+
+	return counts, nil
+}
+
+// InsertGameServerToken records the token minted for the game server, so it can be revoked
+func InsertGameServerToken(ctx context.Context, tokenId uuid.UUID, gameServerId uuid.UUID, expiresAt time.Time) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("insert_game_server_token", time.Now())
+
+	_, err := db.Exec(ctx, `insert into game_server_tokens (id, game_server_id, expires_at) values ($1, $2, $3)`, tokenId, gameServerId, expiresAt)
+	if err != nil {
+		return fmt.Errorf("unable to insert game server token: %v", err)
+	}
+
+	return nil
+}
+
+// RevokeGameServerTokens revokes all tokens of the game server which have not expired yet
+func RevokeGameServerTokens(ctx context.Context, gameServerId uuid.UUID) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("revoke_game_server_tokens", time.Now())
+
+	_, err := db.Exec(ctx, `update game_server_tokens set revoked_at = now() where game_server_id = $1 and revoked_at is null and expires_at > now()`, gameServerId)
+	if err != nil {
+		return fmt.Errorf("unable to revoke game server tokens: %v", err)
+	}
+
+	return nil
+}
+
+// hostPortAllocation is a host port allocated to a named port of a game server, the node is empty until the game server
+// pod has been scheduled
+type hostPortAllocation struct {
+	GameServerId uuid.UUID
+	PortName     string
+	Port         int32
+	NodeName     string
+}
+
+// GetHostPortAllocations returns the host ports allocated to game servers
+func GetHostPortAllocations(ctx context.Context) ([]hostPortAllocation, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_host_port_allocations", time.Now())
+
+	rows, err := db.Query(ctx, `select game_server_id, port_name, port, coalesce(node_name, '') from game_server_host_ports`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get host port allocations: %v", err)
+	}
+	defer rows.Close()
+
+	var allocations []hostPortAllocation
+	for rows.Next() {
+		var allocation hostPortAllocation
+		err = rows.Scan(&allocation.GameServerId, &allocation.PortName, &allocation.Port, &allocation.NodeName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan host port allocation: %v", err)
+		}
+		allocations = append(allocations, allocation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to get host port allocations: %v", err)
+	}
+
+	return allocations, nil
+}
+
+// SetHostPortAllocation allocates the host port to the named port of the game server, replacing its previous port and
+// node
+func SetHostPortAllocation(ctx context.Context, gameServerId uuid.UUID, portName string, port int32) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("set_host_port_allocation", time.Now())
+
+	_, err := db.Exec(ctx, `insert into game_server_host_ports (game_server_id, port_name, port) values ($1, $2, $3)
+on conflict (game_server_id, port_name) do update set port = excluded.port, node_name = null, allocated_at = now()`, gameServerId, portName, port)
+	if err != nil {
+		return fmt.Errorf("unable to set host port allocation: %v", err)
+	}
+
+	return nil
+}
+
+// BindHostPortAllocation sets the node of the host port allocated to the named port of the game server, unless the
+// port is already bound on the node by another game server. Returns the other game server, nil if the allocation has
+// been bound.
+func BindHostPortAllocation(ctx context.Context, gameServerId uuid.UUID, portName string, nodeName string) (uuid.UUID, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("bind_host_port_allocation", time.Now())
+
+	var holder uuid.UUID
+	err := db.QueryRow(ctx, `select o.game_server_id from game_server_host_ports o join game_server_host_ports h on h.port = o.port
+where h.game_server_id = $1 and h.port_name = $2 and o.node_name = $3 and o.game_server_id <> $1 limit 1`, gameServerId, portName, nodeName).Scan(&holder)
+	if err == nil {
+		return holder, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("unable to check host port allocation: %v", err)
+	}
+
+	_, err = db.Exec(ctx, `update game_server_host_ports set node_name = $3 where game_server_id = $1 and port_name = $2 and node_name is distinct from $3`, gameServerId, portName, nodeName)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unable to bind host port allocation: %v", err)
+	}
+
+	return uuid.Nil, nil
+}
+
+// UnbindHostPortAllocations clears the node of the host ports allocated to the game server
+func UnbindHostPortAllocations(ctx context.Context, gameServerId uuid.UUID) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("unbind_host_port_allocations", time.Now())
+
+	_, err := db.Exec(ctx, `update game_server_host_ports set node_name = null where game_server_id = $1`, gameServerId)
+	if err != nil {
+		return fmt.Errorf("unable to unbind host port allocations: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteHostPortAllocations releases the host ports allocated to the game server except the ports of the kept names
+func DeleteHostPortAllocations(ctx context.Context, gameServerId uuid.UUID, keepPortNames []string) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("delete_host_port_allocations", time.Now())
+
+	if keepPortNames == nil {
+		keepPortNames = []string{}
+	}
+
+	_, err := db.Exec(ctx, `delete from game_server_host_ports where game_server_id = $1 and not (port_name = any($2::text[]))`, gameServerId, keepPortNames)
+	if err != nil {
+		return fmt.Errorf("unable to delete host port allocations: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteInactiveHostPortAllocations releases the host ports of game servers which have no gameserver resource and
+// whose record is offline, failed or missing, game servers of the resource ids keep their ports even before their
+// record has been written. Returns the number of released ports.
+func DeleteInactiveHostPortAllocations(ctx context.Context, resourceIds []uuid.UUID) (int64, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return 0, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("delete_inactive_host_port_allocations", time.Now())
+
+	idStrings := make([]string, len(resourceIds))
+	for i, id := range resourceIds {
+		idStrings[i] = id.String()
+	}
+
+	tag, err := db.Exec(ctx, `delete from game_server_host_ports h
+where not (h.game_server_id = any($1::uuid[]))
+  and not exists (select 1 from game_server_v2 s where s.id = h.game_server_id and s.status in ('created', 'starting', 'online'))`, idStrings)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete inactive host port allocations: %v", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// gameServerPortRecord is a port of a game server and where it is reached, the host is empty until it is known
+type gameServerPortRecord struct {
+	Name          string
+	Protocol      string
+	ContainerPort int32
+	Policy        string
+	Host          string
+	Port          int32
+}
+
+// SetGameServerPorts stores the ports of the game server, ports which are no longer declared are removed, unchanged
+// ports are not written
+func SetGameServerPorts(ctx context.Context, gameServerId uuid.UUID, ports []gameServerPortRecord) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("set_game_server_ports", time.Now())
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	names := make([]string, len(ports))
+	for i, port := range ports {
+		names[i] = port.Name
+
+		_, err = tx.Exec(ctx, `insert into game_server_ports (game_server_id, name, protocol, container_port, policy, host, port) values ($1, $2, $3, $4, $5, nullif($6, ''), nullif($7, 0))
+on conflict (game_server_id, name) do update set protocol = excluded.protocol, container_port = excluded.container_port, policy = excluded.policy, host = excluded.host, port = excluded.port
+where (game_server_ports.protocol, game_server_ports.container_port, game_server_ports.policy, game_server_ports.host, game_server_ports.port) is distinct from (excluded.protocol, excluded.container_port, excluded.policy, excluded.host, excluded.port)`,
+			gameServerId, port.Name, port.Protocol, port.ContainerPort, port.Policy, port.Host, port.Port)
+		if err != nil {
+			return fmt.Errorf("unable to set game server port %s: %v", port.Name, err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `delete from game_server_ports where game_server_id = $1 and not (name = any($2::text[]))`, gameServerId, names)
+	if err != nil {
+		return fmt.Errorf("unable to delete game server ports: %v", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to commit game server ports: %v", err)
+	}
+
+	return nil
+}
+
+// GetGameServerPublicPorts returns the public ports of the game server which have been assigned
+func GetGameServerPublicPorts(ctx context.Context, gameServerId uuid.UUID) ([]gameServerPortRecord, error) {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return nil, fmt.Errorf("unable to get database connection")
+	}
+	defer observeDatabaseQuery("get_game_server_public_ports", time.Now())
+
+	rows, err := db.Query(ctx, `select name, protocol, container_port, policy, coalesce(host, ''), port from game_server_ports
+where game_server_id = $1 and policy = 'Public' and port is not null order by name`, gameServerId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get game server ports: %v", err)
+	}
+	defer rows.Close()
+
+	var ports []gameServerPortRecord
+	for rows.Next() {
+		var port gameServerPortRecord
+		err = rows.Scan(&port.Name, &port.Protocol, &port.ContainerPort, &port.Policy, &port.Host, &port.Port)
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan game server port: %v", err)
+		}
+		ports = append(ports, port)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to get game server ports: %v", err)
+	}
+
+	return ports, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// imageReleaseIdPlaceholder is replaced with the release id in the default image
+	imageReleaseIdPlaceholder = "{releaseId}"
+	// imageVersionPlaceholder is replaced with the release version in the default image
+	imageVersionPlaceholder = "{version}"
+)
+
+// GameServerDefaults are operator wide values of the game server settings which are not set in the spec
+type GameServerDefaults struct {
+	// App of game servers without an app, the latest release of the app is used if the release is not set
+	AppId string `json:"appId,omitempty"`
+	// World of game servers without a world
+	WorldId string `json:"worldId,omitempty"`
+	// Maximum number of players of game servers without a limit
+	MaxPlayers int64 `json:"maxPlayers,omitempty"`
+	// Image of game servers without an image, {releaseId} and {version} are replaced with the release of the game server
+	Image string `json:"image,omitempty"`
+	// Images by release id, override the default image
+	ReleaseImages map[string]string `json:"releaseImages,omitempty"`
+}
+
+// loadGameServerDefaults reads the game server defaults from a YAML or JSON file
+func loadGameServerDefaults(path string) (*GameServerDefaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read game server defaults: %v", err)
+	}
+
+	defaults := &GameServerDefaults{}
+	err = yaml.UnmarshalStrict(data, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse game server defaults: %v", err)
+	}
+
+	return defaults, nil
+}
+
+// GameServerDefaulter sets the game server settings left empty in the spec, so the stored spec describes the game
+// server which is actually started
+type GameServerDefaulter struct {
+	defaults *GameServerDefaults
+}
+
+func NewGameServerDefaulter(defaults *GameServerDefaults) *GameServerDefaulter {
+	return &GameServerDefaulter{defaults: defaults}
+}
+
+// Default sets the empty app, release, world, max players and image of the game server, the release is resolved to
+// the latest release of the app. Returns true if the spec has been changed.
+func (d *GameServerDefaulter) Default(ctx context.Context, gameServer *veverseV1.GameServer) (bool, error) {
+	settings := &gameServer.Spec.Settings
+	changed := false
+
+	if settings.App.Id == "" && d.defaults.AppId != "" {
+		settings.App.Id = d.defaults.AppId
+		changed = true
+	}
+
+	if settings.World.Id == "" && d.defaults.WorldId != "" {
+		settings.World.Id = d.defaults.WorldId
+		changed = true
+	}
+
+	if settings.Players.Max == 0 && d.defaults.MaxPlayers > 0 {
+		settings.Players.Max = d.defaults.MaxPlayers
+		changed = true
+	}
+
+	var release *gameServerRelease
+	if settings.Release.Id == "" && settings.App.Id != "" {
+		appId, err := uuid.FromString(settings.App.Id)
+		if err != nil {
+			// invalid ids are rejected by the validating webhook
+			return changed, nil
+		}
+
+		release, err = GetLatestRelease(ctx, appId)
+		if err != nil {
+			return false, fmt.Errorf("failed to get latest release of app %s: %v", appId, err)
+		}
+
+		if release == nil {
+			return false, fmt.Errorf("app %s has no releases", appId)
+		}
+
+		settings.Release.Id = release.Id.String()
+		changed = true
+	}
+
+	if settings.Server.Image == "" && settings.Release.Id != "" {
+		image, err := d.getReleaseImage(ctx, settings.Release.Id, release)
+		if err != nil {
+			return false, err
+		}
+
+		if image != "" {
+			settings.Server.Image = image
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// getReleaseImage returns the image configured for the release or the default image with the release placeholders
+// replaced, the release is read from the database if it has not been resolved yet and the image needs its version
+func (d *GameServerDefaulter) getReleaseImage(ctx context.Context, releaseId string, release *gameServerRelease) (string, error) {
+	if image := d.defaults.ReleaseImages[releaseId]; image != "" {
+		return image, nil
+	}
+
+	image := d.defaults.Image
+	if image == "" {
+		return "", nil
+	}
+
+	if strings.Contains(image, imageVersionPlaceholder) {
+		if release == nil {
+			id, err := uuid.FromString(releaseId)
+			if err != nil {
+				// invalid ids are rejected by the validating webhook
+				return "", nil
+			}
+
+			release, err = GetRelease(ctx, id)
+			if err != nil {
+				return "", fmt.Errorf("failed to get release %s: %v", id, err)
+			}
+
+			if release == nil {
+				return "", fmt.Errorf("release %s not found", id)
+			}
+		}
+
+		if release.Version == "" {
+			return "", fmt.Errorf("release %s has no version", release.Id)
+		}
+
+		image = strings.ReplaceAll(image, imageVersionPlaceholder, release.Version)
+	}
+
+	return strings.ReplaceAll(image, imageReleaseIdPlaceholder, releaseId), nil
+}
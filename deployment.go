@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/gofrs/uuid"
 	appsV1 "k8s.io/api/apps/v1"
 	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
 )
 
 func getGameServerDeploymentClusterResource(ctx context.Context, id uuid.UUID) (*appsV1.Deployment, error) {
@@ -21,39 +26,38 @@ func getGameServerDeploymentClusterResource(ctx context.Context, id uuid.UUID) (
 
 	deployment, err := deploymentsClient.Get(ctx, resourceName, metaV1.GetOptions{})
 	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("failed to get deployment: %v", err)
 	}
 
 	return deployment, nil
 }
 
-func createGameServerDeploymentClusterResource(ctx context.Context, metadata unstructured.Unstructured) error {
-	namespace, ok := ctx.Value("namespace").(string)
-	if !ok {
-		return fmt.Errorf("namespace not found in context")
-	}
-
-	clientset, ok := ctx.Value("clientset").(*kubernetes.Clientset)
-	if !ok {
-		return fmt.Errorf("clientset not found in context")
-	}
+const (
+	// gameServerPortName is the name of the unreal server port of the container and the service
+	gameServerPortName = "unreal"
+	// gameServerPort is the UDP port the unreal server listens on
+	gameServerPort = 7777
+)
 
-	Logger.Infof("metadata: %v", metadata)
+const (
+	// AnnotationSpecHash is the hash of the game server spec and its resolved per-server inputs, used to detect spec changes
+	AnnotationSpecHash = "veverse.com/spec-hash"
+	// AnnotationTemplateHash is the hash of the deployment pod template, which also changes with the operator settings
+	AnnotationTemplateHash = "veverse.com/template-hash"
+)
 
+// buildGameServerDeployment builds the desired deployment of the game server from its spec
+func buildGameServerDeployment(gameServer *veverseV1.GameServer) (*appsV1.Deployment, error) {
 	//region Specification
-	spec, ok := metadata.Object["spec"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("spec not found in game server metadata")
-	}
+	spec := gameServer.Spec
 
 	//region ID
-	if _, ok := spec["id"].(string); !ok {
-		return fmt.Errorf("id not found in game server metadata")
-	}
-
-	id, err := uuid.FromString(spec["id"].(string))
+	id, err := getGameServerId(gameServer)
 	if err != nil {
-		return fmt.Errorf("failed to parse id: %v", err)
+		return nil, err
 	}
 
 	resourceName := getResourceName(id)
@@ -61,16 +65,10 @@ func createGameServerDeploymentClusterResource(ctx context.Context, metadata uns
 
 	//region Environment Variables
 	var envs []apiV1.EnvVar
-	envSpec, ok := spec["env"].([]interface{})
-	if !ok {
-		return fmt.Errorf("envs not found in game server metadata")
-	}
-
-	for _, envMetadata := range envSpec {
-		env := envMetadata.(map[string]interface{})
+	for _, env := range spec.Env {
 		envs = append(envs, apiV1.EnvVar{
-			Name:  env["name"].(string),
-			Value: env["value"].(string),
+			Name:  env.Name,
+			Value: env.Value,
 		})
 	}
 
@@ -79,233 +77,260 @@ func createGameServerDeploymentClusterResource(ctx context.Context, metadata uns
 	//endregion
 
 	//region Settings
-	settingSpec, ok := spec["settings"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("settings not found in game server metadata")
-	}
+	settings := spec.Settings
+
+	// credentials reference the api credentials secret, unless they are still stored in the spec
+	credentialsSecret := getApiCredentialsSecretName(&settings.Api)
+
+	envs = append(envs, apiV1.EnvVar{Name: EnvApiV1Root, Value: settings.Api.V1.Url})
+	envs = append(envs, buildApiCredentialEnv(EnvServerApiV1Key, settings.Api.V1.Key, credentialsSecret, apiCredentialsKeyV1Key))
+	envs = append(envs, apiV1.EnvVar{Name: EnvApiV2Root, Value: settings.Api.V2.Url})
+	envs = append(envs, buildApiCredentialEnv(EnvServerApiV2Email, settings.Api.V2.Email, credentialsSecret, apiCredentialsKeyV2Email))
+	envs = append(envs, buildApiCredentialEnv(EnvServerApiV2Password, settings.Api.V2.Password, credentialsSecret, apiCredentialsKeyV2Password))
+	envs = append(envs, apiV1.EnvVar{Name: EnvServerAppId, Value: settings.App.Id})
+	envs = append(envs, apiV1.EnvVar{Name: EnvServerReleaseId, Value: settings.Release.Id})
+	envs = append(envs, apiV1.EnvVar{Name: EnvServerMaxPlayers, Value: fmt.Sprintf("%d", settings.Players.Max)})
+	envs = append(envs, apiV1.EnvVar{Name: EnvServerWorldId, Value: settings.World.Id})
+	envs = append(envs, apiV1.EnvVar{Name: EnvServerHost, Value: settings.Server.Host})
 
-	//region API
-	apiSettingSpec, ok := settingSpec["api"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("api settings not found in game server metadata")
+	//region Container Image
+	if settings.Server.Image == "" {
+		return nil, fmt.Errorf("server image not found in game server metadata")
 	}
 
-	//region V1
-	apiV1SettingSpec, ok := apiSettingSpec["v1"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("v1 api settings not found in game server metadata")
-	}
-	apiV1Root, ok := apiV1SettingSpec["url"].(string)
-	if !ok {
-		return fmt.Errorf("v1 api root not found in game server metadata")
+	serverImagePullSecrets := make([]apiV1.LocalObjectReference, len(settings.Server.ImagePullSecrets))
+	for i, name := range settings.Server.ImagePullSecrets {
+		serverImagePullSecrets[i] = apiV1.LocalObjectReference{Name: name}
 	}
+	//endregion
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvApiV1Root, Value: apiV1Root})
-
-	apiV1Key, ok := apiV1SettingSpec["key"].(string)
-	if !ok {
-		return fmt.Errorf("v1 api key not found in game server metadata")
+	//region Probes
+	startupProbe, readinessProbe, livenessProbe, probeSidecar, err := buildGameServerProbes(settings.Server.Probes)
+	if err != nil {
+		return nil, err
 	}
+	//endregion
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerApiV1Key, Value: apiV1Key})
 	//endregion
 
-	//region V2
+	//endregion
 
-	apiV2SettingSpec, ok := apiSettingSpec["v2"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("v2 api settings not found in game server metadata")
+	containers := []apiV1.Container{
+		{
+			Name:           resourceName,
+			Env:            envs,
+			Image:          settings.Server.Image,
+			Ports:          buildGameServerContainerPorts(getGameServerPorts(gameServer)),
+			StartupProbe:   startupProbe,
+			ReadinessProbe: readinessProbe,
+			LivenessProbe:  livenessProbe,
+		},
 	}
 
-	apiV2Root, ok := apiV2SettingSpec["url"].(string)
-	if !ok {
-		return fmt.Errorf("v2 api root not found in game server metadata")
+	podLabels := getGameServerLabels(id)
+	if settings.World.Id != "" && len(validation.IsValidLabelValue(settings.World.Id)) == 0 {
+		podLabels[LabelWorldId] = settings.World.Id
 	}
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvApiV2Root, Value: apiV2Root})
-
-	apiV2Email, ok := apiV2SettingSpec["email"].(string)
-	if !ok {
-		return fmt.Errorf("v2 api email not found in game server metadata")
+	podSpec := apiV1.PodSpec{
+		ImagePullSecrets: serverImagePullSecrets,
 	}
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerApiV2Email, Value: apiV2Email})
+	//region Scheduling
+	applyScheduling(&podSpec, &containers[0], getGameServerScheduling(&settings), podLabels[LabelWorldId])
+	//endregion
 
-	apiV2Password, ok := apiV2SettingSpec["password"].(string)
-	if !ok {
-		return fmt.Errorf("v2 api password not found in game server metadata")
+	//region Exposure
+	exposure, err := getGameServerExposure(gameServer)
+	if err != nil {
+		return nil, err
 	}
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerApiV2Password, Value: apiV2Password})
-
+	err = exposure.applyPod(&podSpec, &containers[0], gameServer)
+	if err != nil {
+		return nil, err
+	}
 	//endregion
 
+	//region Drain
+	applyGameServerDrain(&podSpec, &containers[0], settings.Server.Drain)
 	//endregion
 
-	//region App
-	appSpec, ok := settingSpec["app"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("app not found in game server metadata")
+	//region Api Token
+	if gameServerTokens != nil {
+		applyGameServerToken(&podSpec, &containers[0], id)
 	}
-
-	appId, ok := appSpec["id"].(string)
-	if !ok {
-		return fmt.Errorf("app id not found in game server metadata")
-	}
-
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerAppId, Value: appId})
 	//endregion
 
-	//region Release
-	releaseSpec, ok := settingSpec["release"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("release not found in game server metadata")
+	if probeSidecar != nil {
+		containers = append(containers, *probeSidecar)
 	}
+	podSpec.Containers = containers
 
-	releaseId, ok := releaseSpec["id"].(string)
-	if !ok {
-		return fmt.Errorf("release id not found in game server metadata")
+	deployment := &appsV1.Deployment{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            resourceName,
+			Labels:          getGameServerLabels(id),
+			OwnerReferences: []metaV1.OwnerReference{getGameServerOwnerReference(gameServer)},
+		},
+		Spec: appsV1.DeploymentSpec{
+			Replicas: int32Ptr(1),
+			// the previous pod is stopped before the new one starts, both would report to the same game server record
+			Strategy: appsV1.DeploymentStrategy{Type: appsV1.RecreateDeploymentStrategyType},
+			Selector: &metaV1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": resourceName,
+				},
+			},
+			Template: apiV1.PodTemplateSpec{
+				ObjectMeta: metaV1.ObjectMeta{
+					Labels: podLabels,
+				},
+				Spec: podSpec,
+			},
+		},
 	}
 
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerReleaseId, Value: releaseId})
-	//endregion
-
-	//region Players
-	playerSettingSpec, ok := settingSpec["players"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("player settings not found in game server metadata")
+	specHash, err := getGameServerSpecHash(gameServer)
+	if err != nil {
+		return nil, err
 	}
-	Logger.Infof("type of playerSettingSpec: %T", playerSettingSpec["max"])
-	maxPlayers, ok := playerSettingSpec["max"].(int64)
-	if !ok {
-		return fmt.Errorf("max players not found in game server metadata")
+	templateHash, err := getPodTemplateHash(&deployment.Spec.Template)
+	if err != nil {
+		return nil, err
+	}
+	deployment.Annotations = map[string]string{
+		AnnotationSpecHash:     specHash,
+		AnnotationTemplateHash: templateHash,
 	}
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerMaxPlayers, Value: fmt.Sprintf("%d", maxPlayers)})
-	//endregion
 
-	//region World
-	worldSettingSpec, ok := settingSpec["world"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("world settings not found in game server metadata")
+	return deployment, nil
+}
+
+// getGameServerSpecHash returns a hash of the game server spec and the host ports allocated to the game server, changes
+// to them roll the game server pod following its update policy
+func getGameServerSpecHash(gameServer *veverseV1.GameServer) (string, error) {
+	hostPorts := map[string]int32{}
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		if hostPort := getGameServerHostPort(&gameServer.Status, port.Name); hostPort > 0 {
+			hostPorts[port.Name] = hostPort
+		}
 	}
-	worldId, ok := worldSettingSpec["id"].(string)
-	if !ok {
-		return fmt.Errorf("world id not found in game server metadata")
+
+	data, err := json.Marshal(struct {
+		Spec      veverseV1.GameServerSpec `json:"spec"`
+		HostPorts map[string]int32         `json:"hostPorts,omitempty"`
+	}{gameServer.Spec, hostPorts})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal game server spec: %v", err)
 	}
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerWorldId, Value: worldId})
-	//endregion
 
-	//region Server
-	serverSettingSpec, ok := settingSpec["server"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("server settings not found in game server metadata")
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:8]), nil
+}
+
+// getPodTemplateHash returns a hash of the pod template, changes to the template roll the game server pod
+func getPodTemplateHash(template *apiV1.PodTemplateSpec) (string, error) {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal pod template: %v", err)
 	}
 
-	//region Host
-	serverHost, ok := serverSettingSpec["host"].(string)
-	if !ok {
-		return fmt.Errorf("server host not found in game server metadata")
+	hash := sha256.Sum256(data)
+
+	return hex.EncodeToString(hash[:8]), nil
+}
+
+// getDeploymentTemplateHash returns the pod template hash of the deployment, deployments created before the spec and
+// the template were hashed separately have the template hash in the spec hash annotation
+func getDeploymentTemplateHash(deployment *appsV1.Deployment) string {
+	if hash := deployment.Annotations[AnnotationTemplateHash]; hash != "" {
+		return hash
 	}
-	envs = append(envs, apiV1.EnvVar{Name: EnvServerHost, Value: serverHost})
-	//endregion
 
-	//region Container Image
-	serverImage, ok := serverSettingSpec["image"].(string)
+	return deployment.Annotations[AnnotationSpecHash]
+}
+
+// isDeploymentSpecChanged checks if the game server spec has been changed since the deployment has been updated, pod
+// template changes without spec changes come from the operator settings. Deployments created before the spec was
+// hashed separately are treated as unchanged, so upgrading the operator does not restart occupied game servers.
+func isDeploymentSpecChanged(deployment *appsV1.Deployment, desired *appsV1.Deployment) bool {
+	return deployment.Annotations[AnnotationTemplateHash] != "" && deployment.Annotations[AnnotationSpecHash] != desired.Annotations[AnnotationSpecHash]
+}
+
+func createGameServerDeploymentClusterResource(ctx context.Context, deployment *appsV1.Deployment) error {
+	namespace, ok := ctx.Value("namespace").(string)
 	if !ok {
-		return fmt.Errorf("server image not found in game server metadata")
+		return fmt.Errorf("namespace not found in context")
 	}
 
-	serverImagePullSecretsSpec, ok := serverSettingSpec["imagePullSecrets"].([]interface{})
+	clientset, ok := ctx.Value("clientset").(*kubernetes.Clientset)
 	if !ok {
-		return fmt.Errorf("server image pull secrets not found in game server metadata")
+		return fmt.Errorf("clientset not found in context")
 	}
-	serverImagePullSecrets := make([]apiV1.LocalObjectReference, len(serverImagePullSecretsSpec))
-	for i, serverImagePullSecret := range serverImagePullSecretsSpec {
-		name, ok := serverImagePullSecret.(string)
-		if !ok {
-			return fmt.Errorf("server image pull secret is not a string")
-		}
-		serverImagePullSecrets[i] = apiV1.LocalObjectReference{Name: name}
+
+	deploymentsClient := clientset.AppsV1().Deployments(namespace)
+
+	_, err := deploymentsClient.Create(ctx, deployment, metaV1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create deployment: %v", err)
 	}
-	//endregion
 
-	//endregion
+	return nil
+}
 
-	//endregion
+// updateGameServerDeploymentClusterResource applies the desired pod template to the existing deployment, rolling the game server pod
+func updateGameServerDeploymentClusterResource(ctx context.Context, deployment *appsV1.Deployment, desired *appsV1.Deployment) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
 
-	//endregion
+	deployment = deployment.DeepCopy()
+	deployment.Spec.Template = desired.Spec.Template
+	// deployments created with the default rolling update strategy are switched before the pod is rolled
+	deployment.Spec.Strategy = desired.Spec.Strategy
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[AnnotationSpecHash] = desired.Annotations[AnnotationSpecHash]
+	deployment.Annotations[AnnotationTemplateHash] = desired.Annotations[AnnotationTemplateHash]
 
-	deploymentsClient := clientset.AppsV1().Deployments(namespace)
-	deploymentResource := &appsV1.Deployment{
-		ObjectMeta: metaV1.ObjectMeta{
-			Name: resourceName,
-			Labels: map[string]string{
-				"app": resourceName,
-			},
-		},
-		Spec: appsV1.DeploymentSpec{
-			Replicas: int32Ptr(1),
-			Selector: &metaV1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": resourceName,
-				},
-			},
-			Template: apiV1.PodTemplateSpec{
-				ObjectMeta: metaV1.ObjectMeta{
-					Labels: map[string]string{
-						"app": resourceName,
-					},
-				},
-				Spec: apiV1.PodSpec{
-					ImagePullSecrets: serverImagePullSecrets,
-					Containers: []apiV1.Container{
-						{
-							Name:  resourceName,
-							Env:   envs,
-							Image: serverImage,
-							Ports: []apiV1.ContainerPort{
-								{
-									Name:          "unreal",
-									ContainerPort: 7777,
-									Protocol:      "UDP",
-								},
-							},
-						},
-					},
-				},
-			},
-		},
+	_, err := clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update deployment: %v", err)
 	}
-	_, err = deploymentsClient.Create(ctx, deploymentResource, metaV1.CreateOptions{})
+
+	return nil
+}
+
+// adoptGameServerDeploymentClusterResource sets the game server as the controller owner of an existing deployment and
+// adds the game server labels. Deployments without a spec hash get the desired hash without changing their pod template,
+// so adopting them does not restart running game servers.
+func adoptGameServerDeploymentClusterResource(ctx context.Context, deployment *appsV1.Deployment, gameServer *veverseV1.GameServer, desired *appsV1.Deployment) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	id, err := getGameServerId(gameServer)
 	if err != nil {
-		return fmt.Errorf("failed to create deployment: %v", err)
+		return err
 	}
 
-	serviceClient := clientset.CoreV1().Services(namespace)
-	serviceResource := &apiV1.Service{
-		ObjectMeta: metaV1.ObjectMeta{
-			Name: resourceName,
-			Labels: map[string]string{
-				"app": resourceName,
-			},
-		},
-		Spec: apiV1.ServiceSpec{
-			Selector: map[string]string{
-				"app": resourceName,
-			},
-			Ports: []apiV1.ServicePort{
-				{
-					Name:     "unreal",
-					Port:     7777,
-					Protocol: "UDP",
-				},
-			},
-			Type: "NodePort",
-		},
+	deployment = deployment.DeepCopy()
+	if metaV1.GetControllerOf(deployment) == nil {
+		deployment.OwnerReferences = append(deployment.OwnerReferences, getGameServerOwnerReference(gameServer))
 	}
-	_, err = serviceClient.Create(ctx, serviceResource, metaV1.CreateOptions{})
+	setGameServerLabels(&deployment.ObjectMeta, id)
+	if deployment.Annotations[AnnotationSpecHash] == "" {
+		if deployment.Annotations == nil {
+			deployment.Annotations = map[string]string{}
+		}
+		deployment.Annotations[AnnotationSpecHash] = desired.Annotations[AnnotationSpecHash]
+		deployment.Annotations[AnnotationTemplateHash] = desired.Annotations[AnnotationTemplateHash]
+	}
+
+	_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deployment, metaV1.UpdateOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create service: %v", err)
+		return fmt.Errorf("failed to adopt deployment: %v", err)
 	}
 
 	return nil
@@ -320,7 +345,7 @@ func deleteGameServerDeploymentClusterResource(ctx context.Context, id uuid.UUID
 	deploymentsClient := clientset.AppsV1().Deployments(namespace)
 
 	err := deploymentsClient.Delete(ctx, resourceName, metaV1.DeleteOptions{})
-	if err != nil {
+	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete deployment: %v", err)
 	}
 
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/time/rate"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// discord accepts 5 requests per 2 seconds on a webhook and 30 messages per minute in a channel
+	discordRateLimit = rate.Limit(0.5)
+	discordRateBurst = 5
+	// discordMaxEmbeds is the maximum number of embeds of a webhook message
+	discordMaxEmbeds = 10
+	// discordMaxTitleLength, discordMaxDescriptionLength and discordMaxFieldValueLength are the embed limits, the total
+	// length of a message is limited to 6000 characters, which 10 embeds with a short description stay below
+	discordMaxTitleLength       = 256
+	discordMaxDescriptionLength = 400
+	discordMaxFieldValueLength  = 100
+	discordRequestTimeout       = 10 * time.Second
+	discordUsername             = "veverse-server-operator"
+)
+
+var discordSeverityColors = map[NotificationSeverity]int{
+	NotificationSeverityInfo:    0x2ecc71,
+	NotificationSeverityWarning: 0xf1c40f,
+	NotificationSeverityError:   0xe74c3c,
+}
+
+// DiscordSink posts notifications as embeds to a Discord webhook
+type DiscordSink struct {
+	url    string
+	client *http.Client
+}
+
+type discordMessage struct {
+	Username string         `json:"username,omitempty"`
+	Embeds   []discordEmbed `json:"embeds"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Timestamp   string              `json:"timestamp"`
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+func NewDiscordSink(url string) *DiscordSink {
+	return &DiscordSink{
+		url:    url,
+		client: &http.Client{Timeout: discordRequestTimeout},
+	}
+}
+
+func (d *DiscordSink) Name() string {
+	return "discord"
+}
+
+func (d *DiscordSink) MaxBatchSize() int {
+	return discordMaxEmbeds
+}
+
+// Send posts the notifications as a single message, a rate limited request is retried once after the delay requested
+// by Discord
+func (d *DiscordSink) Send(ctx context.Context, notifications []Notification) error {
+	message := discordMessage{Username: discordUsername}
+	for _, notification := range notifications {
+		message.Embeds = append(message.Embeds, buildDiscordEmbed(notification))
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode discord message: %v", err)
+	}
+
+	retryAfter, err := d.post(ctx, body)
+	if err != nil || retryAfter == 0 {
+		return err
+	}
+
+	select {
+	case <-time.After(retryAfter):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	retryAfter, err = d.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return fmt.Errorf("discord webhook is rate limited")
+	}
+
+	return nil
+}
+
+// post sends the message, returns the delay requested by Discord if the request has been rate limited
+func (d *DiscordSink) post(ctx context.Context, body []byte) (time.Duration, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create discord request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call discord webhook: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusTooManyRequests {
+		retryAfter, err := strconv.ParseFloat(response.Header.Get("Retry-After"), 64)
+		if err != nil || retryAfter <= 0 {
+			retryAfter = 1
+		}
+		return time.Duration(retryAfter * float64(time.Second)), nil
+	}
+
+	if response.StatusCode >= 300 {
+		return 0, fmt.Errorf("discord webhook returned %s", response.Status)
+	}
+
+	return 0, nil
+}
+
+func buildDiscordEmbed(notification Notification) discordEmbed {
+	embed := discordEmbed{
+		Title:       truncate(notification.Title, discordMaxTitleLength),
+		Description: truncate(notification.Message, discordMaxDescriptionLength),
+		Color:       discordSeverityColors[notification.Severity],
+		Timestamp:   notification.Time.UTC().Format(time.RFC3339),
+	}
+
+	if !notification.GameServerId.IsNil() {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: "Game server", Value: notification.GameServerId.String()})
+	}
+
+	for _, field := range notification.Fields {
+		// discord rejects fields without a value
+		if field.Value == "" {
+			continue
+		}
+
+		embed.Fields = append(embed.Fields, discordEmbedField{
+			Name:   field.Name,
+			Value:  truncate(field.Value, discordMaxFieldValueLength),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
+
+// truncate shortens the string to at most max runes
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"net/http"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// AnnotationDraining is set to "true" on the game server pod when the game server starts draining
+	AnnotationDraining = "veverse.com/draining"
+
+	// EnvServerAnnotationsFile is the file the pod annotations are mounted to, the game server watches it for the draining annotation
+	EnvServerAnnotationsFile = "VE_SERVER_ANNOTATIONS_FILE"
+	// EnvServerDrainTimeout is the number of seconds the game server has to let its players leave once it starts draining
+	EnvServerDrainTimeout = "VE_SERVER_DRAIN_TIMEOUT"
+
+	defaultDrainTimeout                  = 15 * time.Minute
+	defaultTerminationGracePeriodSeconds = 60
+	// preStopSleepSeconds delays SIGTERM of game servers without an HTTP drain endpoint, so they can notice the draining
+	// annotation of pods deleted outside the drain workflow (e.g. evicted), the game server image must provide sleep
+	preStopSleepSeconds = 5
+	// drainCheckInterval is the time between two player count checks of a draining game server
+	drainCheckInterval = 5 * time.Second
+	// drainRequestTimeout is the timeout of the request to the HTTP drain endpoint
+	drainRequestTimeout = 5 * time.Second
+
+	podInfoVolumeName = "podinfo"
+	podInfoMountPath  = "/etc/podinfo"
+)
+
+// gameServerDrainTimeout is the default drain timeout of game servers, set from the env
+var gameServerDrainTimeout = defaultDrainTimeout
+
+// getGameServerDrainTimeout returns the maximum time the game server waits for its players to leave
+func getGameServerDrainTimeout(settings *veverseV1.DrainSettings) time.Duration {
+	if settings != nil && settings.TimeoutSeconds > 0 {
+		return time.Duration(settings.TimeoutSeconds) * time.Second
+	}
+
+	return gameServerDrainTimeout
+}
+
+// applyGameServerDrain configures the termination of the game server pod: the grace period, the preStop hook and the
+// pod annotations mounted into the game server container, which signal draining
+func applyGameServerDrain(podSpec *apiV1.PodSpec, container *apiV1.Container, settings *veverseV1.DrainSettings) {
+	terminationGracePeriodSeconds := int64(defaultTerminationGracePeriodSeconds)
+	if settings != nil && settings.TerminationGracePeriodSeconds != nil {
+		terminationGracePeriodSeconds = *settings.TerminationGracePeriodSeconds
+	}
+	podSpec.TerminationGracePeriodSeconds = &terminationGracePeriodSeconds
+
+	if settings != nil && settings.Http != nil {
+		container.Lifecycle = &apiV1.Lifecycle{
+			PreStop: &apiV1.LifecycleHandler{
+				HTTPGet: &apiV1.HTTPGetAction{
+					Path: settings.Http.Path,
+					Port: intstr.FromInt(int(settings.Http.Port)),
+				},
+			},
+		}
+	} else {
+		container.Lifecycle = &apiV1.Lifecycle{
+			PreStop: &apiV1.LifecycleHandler{
+				Exec: &apiV1.ExecAction{
+					Command: []string{"sleep", fmt.Sprintf("%d", preStopSleepSeconds)},
+				},
+			},
+		}
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, apiV1.Volume{
+		Name: podInfoVolumeName,
+		VolumeSource: apiV1.VolumeSource{
+			DownwardAPI: &apiV1.DownwardAPIVolumeSource{
+				Items: []apiV1.DownwardAPIVolumeFile{
+					{Path: "annotations", FieldRef: &apiV1.ObjectFieldSelector{FieldPath: "metadata.annotations"}},
+				},
+			},
+		},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, apiV1.VolumeMount{
+		Name:      podInfoVolumeName,
+		MountPath: podInfoMountPath,
+		ReadOnly:  true,
+	})
+
+	container.Env = append(container.Env,
+		apiV1.EnvVar{Name: EnvServerAnnotationsFile, Value: podInfoMountPath + "/annotations"},
+		apiV1.EnvVar{Name: EnvServerDrainTimeout, Value: fmt.Sprintf("%d", int(getGameServerDrainTimeout(settings).Seconds()))},
+	)
+}
+
+// reconcileGameServerDrain removes the deleted game server from allocation and signals it to let its players leave,
+// returns true once no players are connected or the drain timeout has passed
+func reconcileGameServerDrain(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) (bool, time.Time, error) {
+	drainStartedAt, err := StartGameServerDrain(ctx, id)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return false, drainStartedAt, err
+	}
+
+	// game servers without a running pod have no players to wait for
+	if pod == nil || pod.Status.Phase != apiV1.PodRunning {
+		return true, drainStartedAt, nil
+	}
+
+	if pod.Annotations[AnnotationDraining] != "true" {
+		Logger.Infof("draining game server %s", id)
+
+		err = signalGameServerDrain(ctx, pod, gameServer.Spec.Settings.Server.Drain)
+		if err != nil {
+			return false, drainStartedAt, err
+		}
+	}
+
+	players, err := GetGameServerPlayerCount(ctx, id)
+	if err != nil {
+		return false, drainStartedAt, err
+	}
+
+	if players == 0 {
+		Logger.Infof("game server %s has been drained", id)
+		return true, drainStartedAt, nil
+	}
+
+	if timeout := getGameServerDrainTimeout(gameServer.Spec.Settings.Server.Drain); time.Since(drainStartedAt) > timeout {
+		Logger.Warningf("game server %s did not drain within %v, %d players are still connected", id, timeout, players)
+		return true, drainStartedAt, nil
+	}
+
+	return false, drainStartedAt, nil
+}
+
+// signalGameServerDrain annotates the game server pod, the annotation is mounted into the game server container, and
+// calls the HTTP drain endpoint of the game server if it has one
+func signalGameServerDrain(ctx context.Context, pod *apiV1.Pod, settings *veverseV1.DrainSettings) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, AnnotationDraining))
+	_, err := clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metaV1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to annotate pod: %v", err)
+	}
+
+	if settings == nil || settings.Http == nil || pod.Status.PodIP == "" {
+		return nil
+	}
+
+	// the annotation has been set, so a failed request is not retried, the game server still gets the preStop hook call
+	requestCtx, cancel := context.WithTimeout(ctx, drainRequestTimeout)
+	defer cancel()
+
+	// the endpoint is called with GET like the preStop hook, which does not support other methods
+	url := fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, settings.Http.Port, settings.Http.Path)
+	request, err := http.NewRequestWithContext(requestCtx, http.MethodGet, url, nil)
+	if err != nil {
+		Logger.Warningf("failed to create drain request for pod %s: %v", pod.Name, err)
+		return nil
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		Logger.Warningf("failed to call drain endpoint of pod %s: %v", pod.Name, err)
+		return nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		Logger.Warningf("drain endpoint of pod %s returned %s", pod.Name, response.Status)
+	}
+
+	return nil
+}
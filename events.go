@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedCoreV1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	veverseScheme "veverse-server-operator/pkg/client/clientset/versioned/scheme"
+)
+
+// eventComponent is the source of the events recorded by the operator
+const eventComponent = "veverse-server-operator"
+
+// reasons of the events recorded on game server resources
+const (
+	EventReasonDeploymentCreated      = "DeploymentCreated"
+	EventReasonDeploymentCreateFailed = "DeploymentCreateFailed"
+	EventReasonServiceCreated         = "ServiceCreated"
+	EventReasonServiceCreateFailed    = "ServiceCreateFailed"
+	EventReasonPortAssigned           = "PortAssigned"
+	EventReasonPodCrashLoop           = "PodCrashLoop"
+	EventReasonImagePullFailed        = "ImagePullFailed"
+	EventReasonStartTimeout           = "StartTimeout"
+	EventReasonMarkedOffline          = "MarkedOffline"
+	EventReasonSpecInvalid            = "SpecInvalid"
+	EventReasonCredentialsMigrated    = "CredentialsMigrated"
+	EventReasonHostPortConflict       = "HostPortConflict"
+)
+
+// NewEventRecorder creates a recorder writing events to the namespace of the operator, the returned broadcaster has to
+// be shut down to flush pending events
+func NewEventRecorder(ctx context.Context) (record.EventRecorder, record.EventBroadcaster, error) {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	// events reference game server resources, so their kind has to be known to the scheme
+	err := veverseScheme.AddToScheme(scheme.Scheme)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedCoreV1.EventSinkImpl{Interface: clientset.CoreV1().Events(namespace)})
+
+	recorder := broadcaster.NewRecorder(scheme.Scheme, apiV1.EventSource{Component: eventComponent})
+
+	return recorder, broadcaster, nil
+}
+
+// recordGameServerEvent records an event on the game server resource with the recorder of the context, does nothing if
+// the game server resource does not exist
+func recordGameServerEvent(ctx context.Context, gameServer *veverseV1.GameServer, eventType, reason, messageFmt string, args ...interface{}) {
+	recorder, ok := ctx.Value("recorder").(record.EventRecorder)
+	if !ok || gameServer == nil {
+		return
+	}
+
+	recorder.Eventf(gameServer, eventType, reason, messageFmt, args...)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	coreListers "k8s.io/client-go/listers/core/v1"
+	"strings"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+// portPendingCheckInterval is the time between two checks of a port which has not been assigned yet, e.g. while the
+// pod is being scheduled or the load balancer is being provisioned
+const portPendingCheckInterval = 5 * time.Second
+
+// defaultExposureType is the exposure of game servers without an exposure in their spec, set from the env
+var defaultExposureType = veverseV1.ExposureTypeNodePort
+
+// loadBalancerAnnotations are the operator wide annotations of load balancer services, e.g. to select an internal or
+// UDP load balancer of the cloud provider, set from the env
+var loadBalancerAnnotations map[string]string
+
+// portExposure is a strategy exposing the public ports of the game server to players
+type portExposure interface {
+	// describePort names the port assigned by the exposure in events and conditions
+	describePort() string
+	// applyPod changes the game server pod to expose the ports
+	applyPod(podSpec *apiV1.PodSpec, container *apiV1.Container, gameServer *veverseV1.GameServer) error
+	// buildService returns the service of the game server, nil if players connect to the pod without a service
+	buildService(id uuid.UUID, gameServer *veverseV1.GameServer) *apiV1.Service
+	// getEndpoint returns the host players connect to and the ports at the host by port name, a port is missing or zero
+	// until it has been assigned
+	getEndpoint(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, service *apiV1.Service) (string, map[string]int32, error)
+}
+
+var portExposures = map[veverseV1.ExposureType]portExposure{
+	veverseV1.ExposureTypeNodePort:     nodePortExposure{},
+	veverseV1.ExposureTypeHostPort:     hostPortExposure{},
+	veverseV1.ExposureTypeLoadBalancer: loadBalancerExposure{},
+	veverseV1.ExposureTypePodIP:        podIpExposure{},
+}
+
+// parseExposureType parses the operator default exposure, node port if empty
+func parseExposureType(value string) (veverseV1.ExposureType, error) {
+	if value == "" {
+		return veverseV1.ExposureTypeNodePort, nil
+	}
+
+	exposureType := veverseV1.ExposureType(value)
+	if _, ok := portExposures[exposureType]; !ok {
+		return "", fmt.Errorf("unknown exposure type %s", value)
+	}
+
+	return exposureType, nil
+}
+
+// parseAnnotations parses a list of key=value annotations
+func parseAnnotations(values []string) (map[string]string, error) {
+	annotations := map[string]string{}
+	for _, value := range values {
+		key, annotation, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("expected key=value, got %s", value)
+		}
+		annotations[strings.TrimSpace(key)] = strings.TrimSpace(annotation)
+	}
+
+	return annotations, nil
+}
+
+// getGameServerExposureType returns the exposure set in the game server spec or the operator default
+func getGameServerExposureType(gameServer *veverseV1.GameServer) veverseV1.ExposureType {
+	if exposure := gameServer.Spec.Settings.Server.Exposure; exposure != nil && exposure.Type != "" {
+		return exposure.Type
+	}
+
+	return defaultExposureType
+}
+
+// getGameServerExposure returns the exposure strategy of the game server
+func getGameServerExposure(gameServer *veverseV1.GameServer) (portExposure, error) {
+	exposureType := getGameServerExposureType(gameServer)
+
+	exposure, ok := portExposures[exposureType]
+	if !ok {
+		return nil, fmt.Errorf("unknown exposure type %s", exposureType)
+	}
+
+	return exposure, nil
+}
+
+// nodePortExposure exposes the game server with a node port service, players connect to the host of the spec which
+// routes to the cluster nodes
+type nodePortExposure struct{}
+
+func (nodePortExposure) describePort() string {
+	return "node port"
+}
+
+func (nodePortExposure) applyPod(*apiV1.PodSpec, *apiV1.Container, *veverseV1.GameServer) error {
+	return nil
+}
+
+func (nodePortExposure) buildService(id uuid.UUID, gameServer *veverseV1.GameServer) *apiV1.Service {
+	return buildGameServerService(id, gameServer, apiV1.ServiceTypeNodePort, nil)
+}
+
+func (nodePortExposure) getEndpoint(_ context.Context, _ uuid.UUID, gameServer *veverseV1.GameServer, service *apiV1.Service) (string, map[string]int32, error) {
+	if service == nil {
+		return "", nil, nil
+	}
+
+	ports := map[string]int32{}
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		ports[port.Name] = getServiceNodePort(service, port.Name)
+	}
+
+	return gameServer.Spec.Settings.Server.Host, ports, nil
+}
+
+// hostPortExposure binds the host port allocated by the operator on the node running the game server pod, players
+// connect to the address of the node
+type hostPortExposure struct{}
+
+func (hostPortExposure) describePort() string {
+	return "host port"
+}
+
+func (hostPortExposure) applyPod(_ *apiV1.PodSpec, container *apiV1.Container, gameServer *veverseV1.GameServer) error {
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		hostPort := getGameServerHostPort(&gameServer.Status, port.Name)
+		if hostPort == 0 {
+			return fmt.Errorf("host port of the game server port %s has not been allocated", port.Name)
+		}
+
+		for i := range container.Ports {
+			if container.Ports[i].Name == port.Name {
+				container.Ports[i].HostPort = hostPort
+			}
+		}
+	}
+
+	return nil
+}
+
+func (hostPortExposure) buildService(uuid.UUID, *veverseV1.GameServer) *apiV1.Service {
+	return nil
+}
+
+func (hostPortExposure) getEndpoint(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, _ *apiV1.Service) (string, map[string]int32, error) {
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the node is known once the pod has been scheduled
+	if pod == nil || pod.Spec.NodeName == "" {
+		return "", nil, nil
+	}
+
+	host, err := getNodeAddress(ctx, pod.Spec.NodeName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if host == "" {
+		return "", nil, nil
+	}
+
+	ports := map[string]int32{}
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		ports[port.Name] = getGameServerHostPort(&gameServer.Status, port.Name)
+	}
+
+	return host, ports, nil
+}
+
+// getNodeAddress returns the address players use to reach the node, the external address if the node has one
+func getNodeAddress(ctx context.Context, name string) (string, error) {
+	nodeLister := ctx.Value("nodeLister").(coreListers.NodeLister)
+
+	node, err := nodeLister.Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get node %s: %v", name, err)
+	}
+
+	for _, addressType := range []apiV1.NodeAddressType{apiV1.NodeExternalIP, apiV1.NodeExternalDNS, apiV1.NodeInternalIP} {
+		for _, address := range node.Status.Addresses {
+			if address.Type == addressType && address.Address != "" {
+				return address.Address, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// loadBalancerExposure exposes the game server with a load balancer service, players connect to the load balancer
+// address assigned by the cloud provider
+type loadBalancerExposure struct{}
+
+func (loadBalancerExposure) describePort() string {
+	return "load balancer port"
+}
+
+func (loadBalancerExposure) applyPod(*apiV1.PodSpec, *apiV1.Container, *veverseV1.GameServer) error {
+	return nil
+}
+
+func (loadBalancerExposure) buildService(id uuid.UUID, gameServer *veverseV1.GameServer) *apiV1.Service {
+	annotations := map[string]string{}
+	for key, value := range loadBalancerAnnotations {
+		annotations[key] = value
+	}
+	if exposure := gameServer.Spec.Settings.Server.Exposure; exposure != nil {
+		for key, value := range exposure.Annotations {
+			annotations[key] = value
+		}
+	}
+
+	return buildGameServerService(id, gameServer, apiV1.ServiceTypeLoadBalancer, annotations)
+}
+
+func (loadBalancerExposure) getEndpoint(_ context.Context, _ uuid.UUID, gameServer *veverseV1.GameServer, service *apiV1.Service) (string, map[string]int32, error) {
+	if service == nil {
+		return "", nil, nil
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		host := ingress.IP
+		if host == "" {
+			host = ingress.Hostname
+		}
+		if host != "" {
+			return host, getContainerEndpointPorts(gameServer), nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+// podIpExposure lets players connect to the pod IP directly, for clusters with pod networks routable from the clients
+type podIpExposure struct{}
+
+func (podIpExposure) describePort() string {
+	return "pod port"
+}
+
+func (podIpExposure) applyPod(*apiV1.PodSpec, *apiV1.Container, *veverseV1.GameServer) error {
+	return nil
+}
+
+func (podIpExposure) buildService(uuid.UUID, *veverseV1.GameServer) *apiV1.Service {
+	return nil
+}
+
+func (podIpExposure) getEndpoint(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, _ *apiV1.Service) (string, map[string]int32, error) {
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if pod == nil || pod.Status.PodIP == "" {
+		return "", nil, nil
+	}
+
+	return pod.Status.PodIP, getContainerEndpointPorts(gameServer), nil
+}
+
+// getContainerEndpointPorts returns the container ports of the public ports, for exposures reached at the same ports
+func getContainerEndpointPorts(gameServer *veverseV1.GameServer) map[string]int32 {
+	ports := map[string]int32{}
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		ports[port.Name] = port.ContainerPort
+	}
+
+	return ports
+}
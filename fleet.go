@@ -0,0 +1,669 @@
+package main
+
+import (
+	"context"
+	vModel "dev.hackerman.me/artheon/veverse-shared/model"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilRuntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"sort"
+	"sync"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+	veverseInformers "veverse-server-operator/pkg/client/informers/externalversions/veverse/v1"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+const (
+	// LabelFleet is the name of the fleet owning the game server
+	LabelFleet = "veverse.com/fleet"
+	// fleetMaxSurge is the number of game servers a fleet may run above its replicas while replacing outdated game servers
+	fleetMaxSurge = 1
+	// fleetWorkers is the number of fleets reconciled concurrently
+	fleetWorkers = 1
+	// gameServerTypeFleet is the type of game server records created for fleets
+	gameServerTypeFleet = "fleet"
+	// fleetCreationTimeout is the time a created game server has to appear in the informer cache before the fleet stops
+	// waiting for it
+	fleetCreationTimeout = time.Minute
+)
+
+// fleetGameServer is a game server of the fleet with its record state
+type fleetGameServer struct {
+	GameServer *veverseV1.GameServer
+	Id         uuid.UUID
+	Status     string
+	Players    int32
+}
+
+// FleetController converges game server fleets into game server resources and their database records. Work items are
+// fleet names.
+type FleetController struct {
+	queue            workqueue.RateLimitingInterface
+	fleetLister      veverseListers.GameServerFleetLister
+	fleetSynced      cache.InformerSynced
+	gameServerLister veverseListers.GameServerLister
+	gameServerSynced cache.InformerSynced
+	resyncInterval   time.Duration
+
+	// game servers created by the fleet which are not in the informer cache yet, the fleet is not scaled until they
+	// appear, so it does not create them twice
+	creations     map[string]map[string]time.Time
+	creationsLock sync.Mutex
+}
+
+func NewFleetController(fleetInformer veverseInformers.GameServerFleetInformer, gameServerInformer veverseInformers.GameServerInformer, resyncInterval time.Duration) (*FleetController, error) {
+	c := &FleetController{
+		queue:            workqueue.NewNamedRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(reconcileBaseDelay, reconcileMaxDelay), "gameserverfleets"),
+		fleetLister:      fleetInformer.Lister(),
+		fleetSynced:      fleetInformer.Informer().HasSynced,
+		gameServerLister: gameServerInformer.Lister(),
+		gameServerSynced: gameServerInformer.Informer().HasSynced,
+		resyncInterval:   resyncInterval,
+		creations:        map[string]map[string]time.Time{},
+	}
+
+	_, err := fleetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueFleet,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueueFleet(newObj)
+		},
+		DeleteFunc: c.enqueueFleet,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add fleet event handler: %v", err)
+	}
+
+	// game server changes (e.g. becoming online or being deleted) are reflected in the fleet
+	_, err = gameServerInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueGameServerFleet,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueueGameServerFleet(newObj)
+		},
+		DeleteFunc: c.enqueueGameServerFleet,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add game server event handler: %v", err)
+	}
+
+	return c, nil
+}
+
+func (c *FleetController) enqueueFleet(obj interface{}) {
+	// handles deleted objects wrapped in a tombstone as well
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		Logger.Errorf("failed to get fleet key: %v", err)
+		return
+	}
+
+	_, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		Logger.Errorf("failed to split fleet key: %v", err)
+		return
+	}
+
+	c.queue.Add(name)
+}
+
+// enqueueGameServerFleet adds the fleet owning the game server to the queue, game servers not owned by a fleet are ignored
+func (c *FleetController) enqueueGameServerFleet(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	gameServer, ok := obj.(*veverseV1.GameServer)
+	if !ok {
+		Logger.Errorf("failed to convert to game server: %v", obj)
+		return
+	}
+
+	owner := metaV1.GetControllerOf(gameServer)
+	if owner == nil || owner.Kind != "GameServerFleet" || owner.APIVersion != veverseV1.SchemeGroupVersion.String() {
+		return
+	}
+
+	c.queue.Add(owner.Name)
+}
+
+// Run starts the workers and blocks until the context is cancelled
+func (c *FleetController) Run(ctx context.Context, workers int) error {
+	defer utilRuntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	if !cache.WaitForCacheSync(ctx.Done(), c.fleetSynced, c.gameServerSynced) {
+		return fmt.Errorf("failed to sync fleet informer caches")
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+	}
+
+	Logger.Infof("started %d fleet workers", workers)
+	<-ctx.Done()
+
+	return nil
+}
+
+func (c *FleetController) runWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *FleetController) processNextWorkItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	name, ok := item.(string)
+	if !ok {
+		c.queue.Forget(item)
+		Logger.Errorf("unexpected work item: %v", item)
+		return true
+	}
+
+	start := time.Now()
+	requeueAfter, err := c.Reconcile(ctx, name)
+	observeReconcile("fleet", start, err)
+	if err != nil {
+		Logger.Errorf("failed to reconcile fleet %s, retrying: %v", name, err)
+		c.queue.AddRateLimited(item)
+		return true
+	}
+
+	c.queue.Forget(item)
+	if requeueAfter > 0 {
+		c.queue.AddAfter(item, requeueAfter)
+	}
+
+	return true
+}
+
+// Reconcile converges the game servers of the fleet: updates their spec from the template, replaces game servers running
+// an outdated release and scales the fleet to its replicas. It returns the delay after which the fleet should be checked
+// again, player counts do not trigger events, so fleets are always requeued.
+func (c *FleetController) Reconcile(ctx context.Context, name string) (time.Duration, error) {
+	namespace := ctx.Value("namespace").(string)
+
+	fleet, err := c.fleetLister.GameServerFleets(namespace).Get(name)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// game servers of the fleet are garbage collected by the cluster
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get fleet: %v", err)
+	}
+
+	if fleet.DeletionTimestamp != nil {
+		return 0, nil
+	}
+
+	// the fleet is reconciled again once the template without inline api credentials is observed
+	migrated, err := migrateFleetApiCredentials(ctx, fleet)
+	if err != nil {
+		return 0, err
+	}
+	if migrated {
+		return 0, nil
+	}
+
+	gameServers, pending, reconcileErr := c.reconcileGameServers(ctx, fleet)
+
+	// status reflects failed reconciles as well, so it is updated regardless of the reconcile result
+	err = updateFleetStatus(ctx, fleet, gameServers, reconcileErr)
+	if reconcileErr != nil {
+		return 0, reconcileErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if pending {
+		return time.Second, nil
+	}
+
+	return c.resyncInterval, nil
+}
+
+// reconcileGameServers creates, updates and deletes the game servers of the fleet, returns the game servers of the fleet
+// as they were observed before the changes and whether the fleet is waiting for created game servers to be observed
+func (c *FleetController) reconcileGameServers(ctx context.Context, fleet *veverseV1.GameServerFleet) ([]fleetGameServer, bool, error) {
+	err := validateFleetSpec(&fleet.Spec)
+	if err != nil {
+		return nil, false, err
+	}
+
+	gameServers, err := c.getFleetGameServers(ctx, fleet)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var updated, outdated []fleetGameServer
+	var ready int32
+	for _, gameServer := range gameServers {
+		// finished game servers are torn down by the game server controller and replaced by the fleet
+		if !isGameServerActive(gameServer.Status) {
+			continue
+		}
+
+		if gameServer.Status == GameServerStatusOnline {
+			ready++
+		}
+
+		if gameServer.GameServer.Spec.Settings.Release.Id == fleet.Spec.ReleaseId {
+			updated = append(updated, gameServer)
+		} else {
+			outdated = append(outdated, gameServer)
+		}
+	}
+
+	//region Template
+	// template changes other than the release are applied in place, following the update policy of the template
+	for _, gameServer := range updated {
+		desired := buildFleetGameServerSpec(fleet, gameServer.Id)
+		keepFleetGameServerDefaults(&desired, &gameServer.GameServer.Spec)
+		if equality.Semantic.DeepEqual(gameServer.GameServer.Spec, desired) {
+			continue
+		}
+
+		Logger.Infof("updating game server %s of fleet %s", gameServer.Id, fleet.Name)
+		err = updateFleetGameServerClusterResource(ctx, gameServer.GameServer, desired)
+		if err != nil {
+			return gameServers, false, err
+		}
+	}
+	//endregion
+
+	if c.hasPendingCreations(fleet, gameServers) {
+		return gameServers, true, nil
+	}
+
+	replicas := int(fleet.Spec.Replicas)
+
+	//region Scale up
+	// new game servers are created up to the replicas, while replacing outdated game servers the fleet may surge above them
+	create := replicas - len(updated)
+	if surge := replicas + fleetMaxSurge - len(updated) - len(outdated); create > surge {
+		create = surge
+	}
+	for i := 0; i < create; i++ {
+		id, err := createFleetGameServer(ctx, fleet)
+		if err != nil {
+			return gameServers, false, err
+		}
+		c.expectCreation(fleet, id)
+	}
+	//endregion
+
+	//region Scale down
+	// keep enough online game servers while replacing outdated ones, unless the fleet already has more updated game servers
+	// than required, in which case the outdated ones are not needed anyway
+	var remove []fleetGameServer
+	if len(updated) > replicas {
+		sortFleetGameServersForRemoval(updated)
+		remove = append(remove, updated[:len(updated)-replicas]...)
+		remove = append(remove, outdated...)
+	} else {
+		sortFleetGameServersForRemoval(outdated)
+
+		// outdated game servers which are not online do not serve players, so they can always be removed
+		available := ready - int32(replicas)
+		for _, gameServer := range outdated {
+			if gameServer.Status == GameServerStatusOnline {
+				if available <= 0 {
+					continue
+				}
+				available--
+			}
+			remove = append(remove, gameServer)
+		}
+	}
+
+	for _, gameServer := range remove {
+		Logger.Infof("deleting game server %s of fleet %s, status: %s, players: %d", gameServer.Id, fleet.Name, gameServer.Status, gameServer.Players)
+		err = deleteGameServerClusterResource(ctx, gameServer.Id)
+		if err != nil {
+			return gameServers, false, fmt.Errorf("failed to delete game server: %v", err)
+		}
+	}
+	//endregion
+
+	return gameServers, create > 0, nil
+}
+
+// expectCreation records a game server created for the fleet, until it appears in the informer cache
+func (c *FleetController) expectCreation(fleet *veverseV1.GameServerFleet, id uuid.UUID) {
+	c.creationsLock.Lock()
+	defer c.creationsLock.Unlock()
+
+	if c.creations[fleet.Name] == nil {
+		c.creations[fleet.Name] = map[string]time.Time{}
+	}
+	c.creations[fleet.Name][id.String()] = time.Now()
+}
+
+// hasPendingCreations checks if game servers created for the fleet are still missing from the informer cache, game
+// servers which did not appear within the creation timeout are forgotten
+func (c *FleetController) hasPendingCreations(fleet *veverseV1.GameServerFleet, gameServers []fleetGameServer) bool {
+	c.creationsLock.Lock()
+	defer c.creationsLock.Unlock()
+
+	creations := c.creations[fleet.Name]
+	for _, gameServer := range gameServers {
+		delete(creations, gameServer.GameServer.Name)
+	}
+	for name, createdAt := range creations {
+		if time.Since(createdAt) > fleetCreationTimeout {
+			Logger.Warningf("game server %s of fleet %s did not appear within %v", name, fleet.Name, fleetCreationTimeout)
+			delete(creations, name)
+		}
+	}
+	if len(creations) == 0 {
+		delete(c.creations, fleet.Name)
+		return false
+	}
+
+	return true
+}
+
+// getFleetGameServers returns the game servers owned by the fleet which are not being deleted, with their record status
+// and player count
+func (c *FleetController) getFleetGameServers(ctx context.Context, fleet *veverseV1.GameServerFleet) ([]fleetGameServer, error) {
+	gameServers, err := listFleetGameServers(c.gameServerLister, fleet)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []fleetGameServer
+	for _, gameServer := range gameServers {
+		id, err := getGameServerId(gameServer)
+		if err != nil {
+			Logger.Warningf("game server %s of fleet %s has an invalid id: %v", gameServer.Name, fleet.Name, err)
+			continue
+		}
+
+		gameServerRecord, err := GetGameServer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// game servers without a record are marked offline by the game server controller
+		status := GameServerStatusOffline
+		if gameServerRecord != nil {
+			status = gameServerRecord.Status
+		}
+
+		players, err := GetGameServerPlayerCount(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, fleetGameServer{GameServer: gameServer, Id: id, Status: status, Players: players})
+	}
+
+	return result, nil
+}
+
+// listFleetGameServers returns the game server resources owned by the fleet which are not being deleted
+func listFleetGameServers(gameServerLister veverseListers.GameServerLister, fleet *veverseV1.GameServerFleet) ([]*veverseV1.GameServer, error) {
+	selector := labels.SelectorFromSet(labels.Set{LabelFleet: fleet.Name})
+
+	gameServers, err := gameServerLister.GameServers(fleet.Namespace).List(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game servers: %v", err)
+	}
+
+	var result []*veverseV1.GameServer
+	for _, gameServer := range gameServers {
+		if owner := metaV1.GetControllerOf(gameServer); owner == nil || owner.UID != fleet.UID {
+			continue
+		}
+		if gameServer.DeletionTimestamp != nil {
+			continue
+		}
+
+		result = append(result, gameServer)
+	}
+
+	return result, nil
+}
+
+// sortFleetGameServersForRemoval sorts game servers in the order they should be removed: game servers which are not
+// online yet, then empty game servers, then the ones with the fewest players, then the newest ones
+func sortFleetGameServersForRemoval(gameServers []fleetGameServer) {
+	sort.SliceStable(gameServers, func(i, j int) bool {
+		a, b := gameServers[i], gameServers[j]
+
+		if aOnline, bOnline := a.Status == GameServerStatusOnline, b.Status == GameServerStatusOnline; aOnline != bOnline {
+			return !aOnline
+		}
+		if a.Players != b.Players {
+			return a.Players < b.Players
+		}
+
+		return b.GameServer.CreationTimestamp.Before(&a.GameServer.CreationTimestamp)
+	})
+}
+
+// validateFleetSpec checks the ids of the fleet
+func validateFleetSpec(spec *veverseV1.GameServerFleetSpec) error {
+	if _, err := uuid.FromString(spec.WorldId); err != nil {
+		return fmt.Errorf("failed to parse fleet world id: %v", err)
+	}
+	if _, err := uuid.FromString(spec.ReleaseId); err != nil {
+		return fmt.Errorf("failed to parse fleet release id: %v", err)
+	}
+	if spec.AppId != "" {
+		if _, err := uuid.FromString(spec.AppId); err != nil {
+			return fmt.Errorf("failed to parse fleet app id: %v", err)
+		}
+	}
+	if spec.Replicas < 0 {
+		return fmt.Errorf("fleet replicas must not be negative")
+	}
+
+	return nil
+}
+
+// buildFleetGameServerSpec builds the game server spec from the fleet template
+func buildFleetGameServerSpec(fleet *veverseV1.GameServerFleet, id uuid.UUID) veverseV1.GameServerSpec {
+	spec := *fleet.Spec.Template.DeepCopy()
+
+	spec.Id = id.String()
+	spec.Settings.World.Id = fleet.Spec.WorldId
+	spec.Settings.Release.Id = fleet.Spec.ReleaseId
+	if fleet.Spec.AppId != "" {
+		spec.Settings.App.Id = fleet.Spec.AppId
+	}
+
+	return spec
+}
+
+// keepFleetGameServerDefaults copies the settings the fleet template leaves empty from the game server, they are set by
+// the defaulting webhook, the CRD schema defaults and the credentials migration, so the fleet only updates the game
+// server once its template has been changed
+func keepFleetGameServerDefaults(desired *veverseV1.GameServerSpec, current *veverseV1.GameServerSpec) {
+	settings := &desired.Settings
+
+	if settings.App.Id == "" {
+		settings.App.Id = current.Settings.App.Id
+	}
+	if settings.World.Id == "" {
+		settings.World.Id = current.Settings.World.Id
+	}
+	if settings.Players.Max == 0 {
+		settings.Players.Max = current.Settings.Players.Max
+	}
+	if settings.Server.Image == "" {
+		settings.Server.Image = current.Settings.Server.Image
+	}
+	if desired.UpdatePolicy == "" {
+		desired.UpdatePolicy = current.UpdatePolicy
+	}
+
+	for i := range settings.Server.Ports {
+		port := &settings.Server.Ports[i]
+		for _, currentPort := range current.Settings.Server.Ports {
+			if currentPort.Name != port.Name {
+				continue
+			}
+			if port.Protocol == "" {
+				port.Protocol = currentPort.Protocol
+			}
+			if port.Policy == "" {
+				port.Policy = currentPort.Policy
+			}
+		}
+	}
+
+	// the credentials of the template are moved to the secret the game server already references
+	if hasInlineApiCredentials(&settings.Api) && !hasInlineApiCredentials(&current.Settings.Api) && current.Settings.Api.CredentialsSecret != "" {
+		settings.Api = current.Settings.Api
+	}
+}
+
+// createFleetGameServer creates the game server record and the game server resource of a new fleet game server. If the
+// resource can not be created, the record is marked offline by the game server controller.
+func createFleetGameServer(ctx context.Context, fleet *veverseV1.GameServerFleet) (uuid.UUID, error) {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to generate game server id: %v", err)
+	}
+
+	spec := buildFleetGameServerSpec(fleet, id)
+
+	gameServerRecord := &vModel.GameServerV2{
+		Entity: vModel.Entity{
+			Id:     id,
+			Public: true,
+		},
+		ReleaseId:  uuid.FromStringOrNil(fleet.Spec.ReleaseId),
+		WorldId:    uuid.FromStringOrNil(fleet.Spec.WorldId),
+		Type:       gameServerTypeFleet,
+		Host:       spec.Settings.Server.Host,
+		MaxPlayers: int32(spec.Settings.Players.Max),
+	}
+
+	Logger.Infof("creating game server %s of fleet %s", id, fleet.Name)
+
+	err = CreateGameServer(ctx, gameServerRecord)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	gameServer := &veverseV1.GameServer{
+		ObjectMeta: metaV1.ObjectMeta{
+			// game server resources are named after the game server id
+			Name:            id.String(),
+			Labels:          map[string]string{LabelFleet: fleet.Name},
+			OwnerReferences: []metaV1.OwnerReference{*metaV1.NewControllerRef(fleet, veverseV1.SchemeGroupVersion.WithKind("GameServerFleet"))},
+		},
+		Spec: spec,
+	}
+
+	_, err = veverseClientset.VeverseV1().GameServers(namespace).Create(ctx, gameServer, metaV1.CreateOptions{})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create game server: %v", err)
+	}
+
+	return id, nil
+}
+
+func updateFleetGameServerClusterResource(ctx context.Context, gameServer *veverseV1.GameServer, spec veverseV1.GameServerSpec) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	gameServer = gameServer.DeepCopy()
+	gameServer.Spec = spec
+
+	_, err := veverseClientset.VeverseV1().GameServers(namespace).Update(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update game server: %v", err)
+	}
+
+	return nil
+}
+
+// updateFleetStatus writes the observed game servers of the fleet to the fleet status subresource if it has changed
+func updateFleetStatus(ctx context.Context, fleet *veverseV1.GameServerFleet, gameServers []fleetGameServer, reconcileErr error) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	status := fleet.Status.DeepCopy()
+	status.ObservedGeneration = fleet.Generation
+
+	// failed reconciles keep the last observed replicas
+	if reconcileErr == nil {
+		status.Replicas = 0
+		status.ReadyReplicas = 0
+		status.UpdatedReplicas = 0
+		for _, gameServer := range gameServers {
+			if !isGameServerActive(gameServer.Status) {
+				continue
+			}
+
+			status.Replicas++
+			if gameServer.Status == GameServerStatusOnline {
+				status.ReadyReplicas++
+			}
+			if gameServer.GameServer.Spec.Settings.Release.Id == fleet.Spec.ReleaseId {
+				status.UpdatedReplicas++
+			}
+		}
+	}
+
+	if status.Replicas == fleet.Spec.Replicas && status.ReadyReplicas == fleet.Spec.Replicas && status.UpdatedReplicas == fleet.Spec.Replicas {
+		setFleetCondition(status, veverseV1.GameServerFleetConditionReady, metaV1.ConditionTrue, "Ready", "all game servers are online and run the fleet release")
+	} else if status.UpdatedReplicas < status.Replicas {
+		setFleetCondition(status, veverseV1.GameServerFleetConditionReady, metaV1.ConditionFalse, "Updating", fmt.Sprintf("%d of %d game servers run the fleet release", status.UpdatedReplicas, status.Replicas))
+	} else {
+		setFleetCondition(status, veverseV1.GameServerFleetConditionReady, metaV1.ConditionFalse, "Scaling", fmt.Sprintf("%d of %d game servers are online", status.ReadyReplicas, fleet.Spec.Replicas))
+	}
+
+	if reconcileErr != nil {
+		status.LastError = reconcileErr.Error()
+		setFleetCondition(status, veverseV1.GameServerFleetConditionReconciled, metaV1.ConditionFalse, "ReconcileError", reconcileErr.Error())
+	} else {
+		status.LastError = ""
+		setFleetCondition(status, veverseV1.GameServerFleetConditionReconciled, metaV1.ConditionTrue, "Reconciled", "fleet game servers are up to date")
+	}
+
+	if equality.Semantic.DeepEqual(status, &fleet.Status) {
+		return nil
+	}
+
+	fleet = fleet.DeepCopy()
+	fleet.Status = *status
+
+	_, err := veverseClientset.VeverseV1().GameServerFleets(namespace).UpdateStatus(ctx, fleet, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update fleet status: %v", err)
+	}
+
+	return nil
+}
+
+// setFleetCondition sets the condition on the status, the transition time only changes if the condition status changes
+func setFleetCondition(status *veverseV1.GameServerFleetStatus, conditionType string, conditionStatus metaV1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metaV1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: status.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
+}
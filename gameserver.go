@@ -2,22 +2,52 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/gofrs/uuid"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
 )
 
-func getGameServerClusterResource(ctx context.Context, id uuid.UUID) (*unstructured.Unstructured, error) {
+const (
+	// LabelManagedBy marks deployments and services managed by the operator
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelGameServerId is the id of the game server owning the deployment or service
+	LabelGameServerId = "veverse.com/game-server-id"
+	// managedByOperator is the value of the managed by label
+	managedByOperator = "veverse-server-operator"
+)
+
+// gameServerFinalizer keeps the game server resource until the operator has marked its record offline
+const gameServerFinalizer = "veverse.com/game-server-offline"
+
+// getGameServerId parses the game server UUID from the game server spec
+func getGameServerId(gameServer *veverseV1.GameServer) (uuid.UUID, error) {
+	if gameServer.Spec.Id == "" {
+		return uuid.Nil, fmt.Errorf("id not found in game server metadata")
+	}
+
+	id, err := uuid.FromString(gameServer.Spec.Id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to parse id: %v", err)
+	}
+
+	return id, nil
+}
+
+func getGameServerClusterResource(ctx context.Context, id uuid.UUID) (*veverseV1.GameServer, error) {
 	namespace := ctx.Value("namespace").(string)
-	dynamicClient := ctx.Value("dynamicClient").(*dynamic.DynamicClient)
-	gameServerResource := ctx.Value("gameServerResource").(schema.GroupVersionResource)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
 
-	resourceName := getResourceName(id)
+	// game server resources are named after the game server id, unlike their deployments and services
+	resourceName := id.String()
 
-	gameServer, err := dynamicClient.Resource(gameServerResource).Namespace(namespace).Get(ctx, resourceName, metaV1.GetOptions{})
+	gameServer, err := veverseClientset.VeverseV1().GameServers(namespace).Get(ctx, resourceName, metaV1.GetOptions{})
 	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
 		return nil, err
 	}
 
@@ -26,15 +56,88 @@ func getGameServerClusterResource(ctx context.Context, id uuid.UUID) (*unstructu
 
 func deleteGameServerClusterResource(ctx context.Context, id uuid.UUID) error {
 	namespace := ctx.Value("namespace").(string)
-	dynamicClient := ctx.Value("dynamicClient").(*dynamic.DynamicClient)
-	gameServerResource := ctx.Value("gameServerResource").(schema.GroupVersionResource)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
 
-	resourceName := getResourceName(id)
+	// game server resources are named after the game server id, unlike their deployments and services
+	resourceName := id.String()
 
-	err := dynamicClient.Resource(gameServerResource).Namespace(namespace).Delete(ctx, resourceName, metaV1.DeleteOptions{})
-	if err != nil {
+	err := veverseClientset.VeverseV1().GameServers(namespace).Delete(ctx, resourceName, metaV1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
 		return err
 	}
 
 	return nil
 }
+
+// getGameServerLabels returns the labels of resources created for the game server
+func getGameServerLabels(id uuid.UUID) map[string]string {
+	return map[string]string{
+		"app":             getResourceName(id),
+		LabelManagedBy:    managedByOperator,
+		LabelGameServerId: id.String(),
+	}
+}
+
+// setGameServerLabels adds the game server labels to the existing labels of a resource
+func setGameServerLabels(objectMeta *metaV1.ObjectMeta, id uuid.UUID) {
+	if objectMeta.Labels == nil {
+		objectMeta.Labels = map[string]string{}
+	}
+
+	for key, value := range getGameServerLabels(id) {
+		objectMeta.Labels[key] = value
+	}
+}
+
+// getGameServerOwnerReference returns the controller owner reference set on resources created for the game server,
+// so they are garbage collected together with the game server
+func getGameServerOwnerReference(gameServer *veverseV1.GameServer) metaV1.OwnerReference {
+	return *metaV1.NewControllerRef(gameServer, veverseV1.SchemeGroupVersion.WithKind("GameServer"))
+}
+
+func hasGameServerFinalizer(gameServer *veverseV1.GameServer) bool {
+	for _, finalizer := range gameServer.Finalizers {
+		if finalizer == gameServerFinalizer {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addGameServerFinalizer(ctx context.Context, gameServer *veverseV1.GameServer) (*veverseV1.GameServer, error) {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	gameServer = gameServer.DeepCopy()
+	gameServer.Finalizers = append(gameServer.Finalizers, gameServerFinalizer)
+
+	gameServer, err := veverseClientset.VeverseV1().GameServers(namespace).Update(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add game server finalizer: %v", err)
+	}
+
+	return gameServer, nil
+}
+
+func removeGameServerFinalizer(ctx context.Context, gameServer *veverseV1.GameServer) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	gameServer = gameServer.DeepCopy()
+
+	finalizers := make([]string, 0, len(gameServer.Finalizers))
+	for _, finalizer := range gameServer.Finalizers {
+		if finalizer != gameServerFinalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	gameServer.Finalizers = finalizers
+
+	_, err := veverseClientset.VeverseV1().GameServers(namespace).Update(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove game server finalizer: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+#!/usr/bin/env bash
+
+# Regenerates deepcopy functions, the typed clientset, listers and informers for the operator custom resources.
+# Requires the code generator binaries, install them with:
+#   go install k8s.io/code-generator/cmd/{deepcopy-gen,client-gen,lister-gen,informer-gen}@v0.26.1
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+MODULE=veverse-server-operator
+APIS_PKG=${MODULE}/pkg/apis
+OUTPUT_PKG=${MODULE}/pkg/client
+GROUPS_WITH_VERSIONS="veverse:v1"
+
+GOBIN="$(go env GOBIN)"
+gobin="${GOBIN:-$(go env GOPATH)/bin}"
+
+# generators expect a GOPATH-like layout, so generate into a temporary directory and copy the result back
+OUTPUT_BASE=$(mktemp -d)
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+FQ_APIS=()
+for GVs in ${GROUPS_WITH_VERSIONS}; do
+  IFS=: read -r G Vs <<<"${GVs}"
+  for V in ${Vs//,/ }; do
+    FQ_APIS+=("${APIS_PKG}/${G}/${V}")
+  done
+done
+INPUT_DIRS=$(IFS=,; echo "${FQ_APIS[*]}")
+
+cd "${SCRIPT_ROOT}"
+
+"${gobin}/deepcopy-gen" \
+  --input-dirs "${INPUT_DIRS}" \
+  -O zz_generated.deepcopy \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+"${gobin}/client-gen" \
+  --clientset-name versioned \
+  --input-base "" \
+  --input "${INPUT_DIRS}" \
+  --output-package "${OUTPUT_PKG}/clientset" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+"${gobin}/lister-gen" \
+  --input-dirs "${INPUT_DIRS}" \
+  --output-package "${OUTPUT_PKG}/listers" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+"${gobin}/informer-gen" \
+  --input-dirs "${INPUT_DIRS}" \
+  --versioned-clientset-package "${OUTPUT_PKG}/clientset/versioned" \
+  --listers-package "${OUTPUT_PKG}/listers" \
+  --output-package "${OUTPUT_PKG}/informers" \
+  --output-base "${OUTPUT_BASE}" \
+  --go-header-file "${SCRIPT_ROOT}/hack/boilerplate.go.txt"
+
+rm -rf "${SCRIPT_ROOT}/pkg/client"
+cp -R "${OUTPUT_BASE}/${MODULE}/pkg/." "${SCRIPT_ROOT}/pkg/"
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"k8s.io/client-go/tools/cache"
+	"net/http"
+	"time"
+)
+
+const (
+	// databasePingTimeout is the timeout of the database ping of a health check
+	databasePingTimeout = 2 * time.Second
+	// defaultInformerSyncTimeout is the time the informer caches have to sync after the start before the operator is
+	// considered unhealthy
+	defaultInformerSyncTimeout = 2 * time.Minute
+)
+
+// Health serves the liveness and readiness endpoints. Both check the database connection and the informer caches, the
+// liveness endpoint fails as well if the leader did not complete a reconcile pass within the maximum reconcile age, so a
+// stuck operator is restarted.
+type Health struct {
+	informersSynced map[string]cache.InformerSynced
+	controller      *Controller
+	leaderElector   *LeaderElector
+	// maxReconcileAge is the maximum time since the last successful reconcile pass of the leader
+	maxReconcileAge time.Duration
+	// syncTimeout is the time the informer caches have to sync before the liveness endpoint fails
+	syncTimeout time.Duration
+	startedAt   time.Time
+}
+
+func NewHealth(informersSynced map[string]cache.InformerSynced, controller *Controller, leaderElector *LeaderElector, maxReconcileAge time.Duration, syncTimeout time.Duration) *Health {
+	return &Health{
+		informersSynced: informersSynced,
+		controller:      controller,
+		leaderElector:   leaderElector,
+		maxReconcileAge: maxReconcileAge,
+		syncTimeout:     syncTimeout,
+		startedAt:       time.Now(),
+	}
+}
+
+// ServeLiveness fails if the database is unreachable, the informer caches did not sync within the sync timeout or the
+// leader stopped reconciling
+func (h *Health) ServeLiveness(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]error{
+		"database":  h.checkDatabase(r.Context()),
+		"reconcile": h.checkReconcile(),
+	}
+
+	// caches are still syncing after a start, which is reported by the readiness endpoint only
+	if time.Since(h.startedAt) > h.syncTimeout {
+		checks["informers"] = h.checkInformers()
+	}
+
+	writeHealth(w, checks)
+}
+
+// ServeReadiness fails if the database is unreachable or the informer caches have not synced yet, instances which are not
+// ready do not receive allocation requests
+func (h *Health) ServeReadiness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, map[string]error{
+		"database":  h.checkDatabase(r.Context()),
+		"informers": h.checkInformers(),
+	})
+}
+
+func (h *Health) checkDatabase(ctx context.Context) error {
+	db, ok := ctx.Value("database").(*pgxpool.Pool)
+	if !ok {
+		return fmt.Errorf("unable to get database connection")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, databasePingTimeout)
+	defer cancel()
+
+	err := db.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to ping database: %v", err)
+	}
+
+	return nil
+}
+
+func (h *Health) checkInformers() error {
+	for name, synced := range h.informersSynced {
+		if !synced() {
+			return fmt.Errorf("%s informer cache has not synced", name)
+		}
+	}
+
+	return nil
+}
+
+// checkReconcile fails if the leader did not complete a reconcile pass within the maximum reconcile age, instances which
+// are not leading do not reconcile
+func (h *Health) checkReconcile() error {
+	if !h.leaderElector.IsLeader() {
+		return nil
+	}
+
+	lastReconcileAt := h.controller.LastReconcileAt()
+	if lastReconcileAt.IsZero() {
+		// the controller has not started yet after acquiring the lease
+		return nil
+	}
+
+	if age := time.Since(lastReconcileAt); age > h.maxReconcileAge {
+		return fmt.Errorf("last successful reconcile pass was %v ago", age.Round(time.Second))
+	}
+
+	return nil
+}
+
+// writeHealth writes the result of each check, responds with 503 if any check failed
+func writeHealth(w http.ResponseWriter, checks map[string]error) {
+	statusCode := http.StatusOK
+	result := map[string]string{}
+	for name, err := range checks {
+		if err != nil {
+			statusCode = http.StatusServiceUnavailable
+			result[name] = err.Error()
+			Logger.Warningf("health check %s failed: %v", name, err)
+		} else {
+			result[name] = "ok"
+		}
+	}
+
+	writeJson(w, statusCode, result)
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreListers "k8s.io/client-go/listers/core/v1"
+	"sync"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+// defaultHostPortRange is the range host ports are allocated from, below the service node port range
+const defaultHostPortRange = "7000-8000"
+
+// hostPorts allocates the host ports of game servers exposed with host ports, set up in main
+var hostPorts *HostPortAllocator
+
+// HostPortAllocator assigns host ports from the operator range to the public ports of game servers exposed with host
+// ports. Allocations are stored in the game_server_host_ports table, so they survive operator restarts and leader
+// changes. A port is allocated before the game server pod is scheduled, the least used port of the range is picked, so
+// ports are unique until the range is exhausted and are then shared by at most as many game servers as there are
+// nodes. The scheduler places pods sharing a port on different nodes, the node is recorded once the pod has been
+// scheduled.
+type HostPortAllocator struct {
+	// mu serializes allocations, the allocations are only changed by the leader
+	mu               sync.Mutex
+	first            int32
+	last             int32
+	nodeLister       coreListers.NodeLister
+	gameServerLister veverseListers.GameServerLister
+}
+
+func NewHostPortAllocator(nodeLister coreListers.NodeLister, gameServerLister veverseListers.GameServerLister, portRange string) (*HostPortAllocator, error) {
+	first, last, err := parsePortRange(portRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host port range %s: %v", portRange, err)
+	}
+
+	return &HostPortAllocator{
+		first:            first,
+		last:             last,
+		nodeLister:       nodeLister,
+		gameServerLister: gameServerLister,
+	}, nil
+}
+
+// Allocate returns the host port allocated to the named port of the game server, a port is allocated if it has none or
+// its port has to be avoided. The current port is kept if it is still available, e.g. for game servers allocated
+// before the allocations were stored.
+func (a *HostPortAllocator) Allocate(ctx context.Context, id uuid.UUID, name string, current int32, avoid int32) (int32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	allocations, err := GetHostPortAllocations(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, allocation := range allocations {
+		if allocation.GameServerId == id && allocation.PortName == name && allocation.Port != avoid {
+			return allocation.Port, nil
+		}
+	}
+
+	// ports of game servers which went offline or were deleted while the operator was not running are reused
+	resourceIds, err := a.getGameServerIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	released, err := DeleteInactiveHostPortAllocations(ctx, resourceIds)
+	if err != nil {
+		return 0, err
+	}
+	if released > 0 {
+		Logger.Infof("released %d host ports of inactive game servers", released)
+
+		allocations, err = GetHostPortAllocations(ctx)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	nodes, err := a.countNodes()
+	if err != nil {
+		return 0, err
+	}
+
+	port := a.selectPort(allocations, id, name, current, avoid, nodes)
+	if port == 0 {
+		return 0, fmt.Errorf("no free host ports left in range %d-%d on %d nodes", a.first, a.last, nodes)
+	}
+
+	err = SetHostPortAllocation(ctx, id, name, port)
+	if err != nil {
+		return 0, err
+	}
+
+	return port, nil
+}
+
+// selectPort picks the host port of the named port of the game server, the current port is kept if no other game
+// server uses it, otherwise the least used port of the range is picked. Ports used on every node, the other ports of
+// the game server and the avoided port are not available. Returns zero if no port is available.
+func (a *HostPortAllocator) selectPort(allocations []hostPortAllocation, id uuid.UUID, name string, current int32, avoid int32, nodes int) int32 {
+	// the ports of a game server are bound on the same node, so they must not share a port
+	usage := map[int32]int{}
+	own := map[int32]bool{}
+	for _, allocation := range allocations {
+		if allocation.GameServerId != id {
+			usage[allocation.Port]++
+		} else if allocation.PortName != name {
+			own[allocation.Port] = true
+		}
+	}
+
+	isAvailable := func(port int32) bool {
+		return port >= a.first && port <= a.last && port != avoid && !own[port] && usage[port] < nodes
+	}
+
+	if isAvailable(current) && usage[current] == 0 {
+		return current
+	}
+
+	port := int32(0)
+	for candidate := a.first; candidate <= a.last; candidate++ {
+		if isAvailable(candidate) && (port == 0 || usage[candidate] < usage[port]) {
+			port = candidate
+		}
+	}
+
+	return port
+}
+
+// Bind records the node the game server pod has been scheduled to for the named port, returns the game server which
+// has the port bound on the node already, nil if the port has been bound
+func (a *HostPortAllocator) Bind(ctx context.Context, id uuid.UUID, name string, nodeName string) (uuid.UUID, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return BindHostPortAllocation(ctx, id, name, nodeName)
+}
+
+// Unbind clears the node of the game server ports, e.g. once its pod has moved to another node
+func (a *HostPortAllocator) Unbind(ctx context.Context, id uuid.UUID) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return UnbindHostPortAllocations(ctx, id)
+}
+
+// Release frees the host ports of the game server except the ports of the kept names
+func (a *HostPortAllocator) Release(ctx context.Context, id uuid.UUID, keepPortNames ...string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return DeleteHostPortAllocations(ctx, id, keepPortNames)
+}
+
+// countNodes returns the number of schedulable nodes, at least one, so ports are allocated before the nodes are known
+func (a *HostPortAllocator) countNodes() (int, error) {
+	nodes, err := a.nodeLister.List(labels.Everything())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	count := 0
+	for _, node := range nodes {
+		if !node.Spec.Unschedulable {
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 1, nil
+	}
+
+	return count, nil
+}
+
+// getGameServerIds returns the ids of the gameserver resources, their records may not have been written yet
+func (a *HostPortAllocator) getGameServerIds(ctx context.Context) ([]uuid.UUID, error) {
+	namespace := ctx.Value("namespace").(string)
+
+	gameServers, err := a.gameServerLister.GameServers(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game servers: %v", err)
+	}
+
+	var ids []uuid.UUID
+	for _, gameServer := range gameServers {
+		id, err := uuid.FromString(gameServer.Spec.Id)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// getPodHostPort returns the host port bound by the named port of the pod, zero if it has none
+func getPodHostPort(pod *apiV1.Pod, name string) int32 {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.Name == name {
+				return port.HostPort
+			}
+		}
+	}
+
+	return 0
+}
+
+// isHostPortBound checks if a container of the pod binds the host port
+func isHostPortBound(pod *apiV1.Pod, hostPort int32) bool {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.HostPort == hostPort {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// reconcileGameServerHostPort allocates the host ports of the public ports of game servers exposed with host ports and
+// releases them once the ports or the exposure have been changed, the ports are stored in the game server status
+// before the deployment binds them. Returns the updated game server.
+func reconcileGameServerHostPort(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) (*veverseV1.GameServer, error) {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	allocated := map[string]int32{}
+	if getGameServerExposureType(gameServer) == veverseV1.ExposureTypeHostPort {
+		var names []string
+		for _, port := range getPublicGameServerPorts(gameServer) {
+			hostPort, err := hostPorts.Allocate(ctx, id, port.Name, getGameServerHostPort(&gameServer.Status, port.Name), 0)
+			if err != nil {
+				return nil, err
+			}
+
+			hostPort, err = bindGameServerHostPort(ctx, id, gameServer, port.Name, hostPort)
+			if err != nil {
+				return nil, err
+			}
+
+			allocated[port.Name] = hostPort
+			names = append(names, port.Name)
+		}
+
+		if hasStaleHostPorts(&gameServer.Status, allocated) {
+			err := hostPorts.Release(ctx, id, names...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if hasStaleHostPorts(&gameServer.Status, allocated) {
+		err := hostPorts.Release(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := gameServer.Status.DeepCopy()
+	setGameServerHostPorts(status, getGameServerPorts(gameServer), allocated)
+	if equality.Semantic.DeepEqual(status, &gameServer.Status) {
+		return gameServer, nil
+	}
+
+	gameServer = gameServer.DeepCopy()
+	gameServer.Status = *status
+
+	gameServer, err := veverseClientset.VeverseV1().GameServers(namespace).UpdateStatus(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update game server host ports: %v", err)
+	}
+
+	return gameServer, nil
+}
+
+// hasStaleHostPorts checks if the status has host ports which are not allocated to the game server anymore
+func hasStaleHostPorts(status *veverseV1.GameServerStatus, allocated map[string]int32) bool {
+	if status.HostPort != 0 && status.HostPort != allocated[gameServerPortName] {
+		return true
+	}
+
+	for _, port := range status.Ports {
+		if port.HostPort != 0 && port.HostPort != allocated[port.Name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setGameServerHostPorts sets the allocated host ports in the status, ports which are missing from the status are
+// added, the other port fields are set by the status update
+func setGameServerHostPorts(status *veverseV1.GameServerStatus, ports []veverseV1.GameServerPort, allocated map[string]int32) {
+	status.HostPort = allocated[gameServerPortName]
+
+	for i := range status.Ports {
+		status.Ports[i].HostPort = allocated[status.Ports[i].Name]
+	}
+
+	for _, port := range ports {
+		if allocated[port.Name] == 0 || getGameServerHostPort(status, port.Name) != 0 {
+			continue
+		}
+
+		status.Ports = append(status.Ports, veverseV1.GameServerPortStatus{
+			Name:          port.Name,
+			Protocol:      port.Protocol,
+			ContainerPort: port.ContainerPort,
+			Policy:        port.Policy,
+			HostPort:      allocated[port.Name],
+		})
+	}
+}
+
+// bindGameServerHostPort records the node of the host port once the game server pod binding it has been scheduled.
+// Bindings of other game servers whose pods no longer bind the port on the node are stale and are cleared, otherwise
+// the port is in conflict and the game server port gets a new host port. Returns the host port of the named port.
+func bindGameServerHostPort(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, name string, hostPort int32) (int32, error) {
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	// pods of the previous pod template may still bind the previous port
+	if pod == nil || pod.Spec.NodeName == "" || getPodHostPort(pod, name) != hostPort {
+		return hostPort, nil
+	}
+
+	holder, err := hostPorts.Bind(ctx, id, name, pod.Spec.NodeName)
+	if err != nil {
+		return 0, err
+	}
+	if holder == uuid.Nil {
+		return hostPort, nil
+	}
+
+	holderPod, err := getGameServerPodClusterResource(ctx, holder)
+	if err != nil {
+		return 0, err
+	}
+
+	if holderPod == nil || holderPod.Spec.NodeName != pod.Spec.NodeName || !isHostPortBound(holderPod, hostPort) {
+		Logger.Infof("clearing stale host port bindings of game server %s on node %s", holder, pod.Spec.NodeName)
+		err = hostPorts.Unbind(ctx, holder)
+		if err != nil {
+			return 0, err
+		}
+
+		holder, err = hostPorts.Bind(ctx, id, name, pod.Spec.NodeName)
+		if err != nil {
+			return 0, err
+		}
+		if holder == uuid.Nil {
+			return hostPort, nil
+		}
+	}
+
+	recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonHostPortConflict, "host port %d of port %s on node %s is bound by game server %s, allocating a new port", hostPort, name, pod.Spec.NodeName, holder)
+
+	return hostPorts.Allocate(ctx, id, name, 0, hostPort)
+}
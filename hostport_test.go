@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/gofrs/uuid"
+	"testing"
+)
+
+func TestHostPortAllocatorSelectPort(t *testing.T) {
+	id := uuid.Must(uuid.FromString("00000000-0000-0000-0000-000000000001"))
+	other := uuid.Must(uuid.FromString("00000000-0000-0000-0000-000000000002"))
+	another := uuid.Must(uuid.FromString("00000000-0000-0000-0000-000000000003"))
+
+	allocator := &HostPortAllocator{first: 7000, last: 7002}
+
+	tests := []struct {
+		name        string
+		allocations []hostPortAllocation
+		current     int32
+		avoid       int32
+		nodes       int
+		want        int32
+	}{
+		{name: "first port", nodes: 1, want: 7000},
+		{name: "current port is kept", current: 7001, nodes: 1, want: 7001},
+		{name: "current port out of range", current: 8000, nodes: 1, want: 7000},
+		{
+			name:        "current port used by another game server",
+			allocations: []hostPortAllocation{{GameServerId: other, PortName: gameServerPortName, Port: 7000}},
+			current:     7000,
+			nodes:       2,
+			want:        7001,
+		},
+		{
+			name:        "own allocation of the port is not counted",
+			allocations: []hostPortAllocation{{GameServerId: id, PortName: gameServerPortName, Port: 7000}},
+			current:     7000,
+			nodes:       1,
+			want:        7000,
+		},
+		{
+			name:        "other ports of the game server are not shared",
+			allocations: []hostPortAllocation{{GameServerId: id, PortName: "beacon", Port: 7000}},
+			nodes:       2,
+			want:        7001,
+		},
+		{
+			name: "unused port is preferred",
+			allocations: []hostPortAllocation{
+				{GameServerId: other, PortName: gameServerPortName, Port: 7000},
+				{GameServerId: another, PortName: gameServerPortName, Port: 7001},
+			},
+			nodes: 2,
+			want:  7002,
+		},
+		{
+			name: "least used port is shared",
+			allocations: []hostPortAllocation{
+				{GameServerId: other, PortName: gameServerPortName, Port: 7000},
+				{GameServerId: other, PortName: "beacon", Port: 7001},
+				{GameServerId: other, PortName: "query", Port: 7002},
+				{GameServerId: another, PortName: gameServerPortName, Port: 7000},
+				{GameServerId: another, PortName: "beacon", Port: 7002},
+			},
+			nodes: 3,
+			want:  7001,
+		},
+		{
+			name: "ports used on every node are not available",
+			allocations: []hostPortAllocation{
+				{GameServerId: other, PortName: gameServerPortName, Port: 7000},
+				{GameServerId: other, PortName: "beacon", Port: 7001},
+				{GameServerId: other, PortName: "query", Port: 7002},
+			},
+			nodes: 1,
+			want:  0,
+		},
+		{name: "avoided port", avoid: 7000, nodes: 1, want: 7001},
+		{name: "avoided current port", current: 7001, avoid: 7001, nodes: 1, want: 7000},
+		{
+			name: "avoided port is the only available port",
+			allocations: []hostPortAllocation{
+				{GameServerId: other, PortName: gameServerPortName, Port: 7001},
+				{GameServerId: other, PortName: "beacon", Port: 7002},
+			},
+			avoid: 7000,
+			nodes: 1,
+			want:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := allocator.selectPort(test.allocations, id, gameServerPortName, test.current, test.avoid, test.nodes)
+			if got != test.want {
+				t.Errorf("got %d, want %d", got, test.want)
+			}
+		})
+	}
+}
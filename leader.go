@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// leaseName is the name of the lease held by the operator instance which reconciles game servers
+	leaseName = "veverse-server-operator"
+
+	// a new instance takes over at most leaseDuration after the leader stopped renewing, or immediately if the leader
+	// released the lease on shutdown
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// LeaderElector runs the reconcile loops only while this operator instance holds the lease, so instances running side by
+// side during rollouts do not reconcile the same game servers
+type LeaderElector struct {
+	identity string
+	elector  *leaderelection.LeaderElector
+}
+
+// NewLeaderElector creates a leader elector calling run once this instance becomes the leader, the context passed to run
+// is cancelled when the leadership is lost
+func NewLeaderElector(ctx context.Context, run func(ctx context.Context)) (*LeaderElector, error) {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	// pod name set by the downward API, unique for each operator instance
+	identity := os.Getenv("POD_NAME")
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname: %v", err)
+		}
+		identity = hostname
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metaV1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: namespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: getEnvDuration("LEADER_ELECTION_LEASE_DURATION", defaultLeaseDuration),
+		RenewDeadline: getEnvDuration("LEADER_ELECTION_RENEW_DEADLINE", defaultRenewDeadline),
+		RetryPeriod:   getEnvDuration("LEADER_ELECTION_RETRY_PERIOD", defaultRetryPeriod),
+		// release the lease on shutdown, so the next instance does not wait for it to expire
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				Logger.Infof("%s started leading", identity)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				Logger.Infof("%s stopped leading", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					Logger.Infof("%s is the leader", leader)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader elector: %v", err)
+	}
+
+	return &LeaderElector{identity: identity, elector: elector}, nil
+}
+
+// Run campaigns for the lease until the context is cancelled. Leadership is only acquired once, an instance which lost
+// it returns, so it restarts with fresh state instead of reconciling next to the new leader.
+func (l *LeaderElector) Run(ctx context.Context) {
+	l.elector.Run(ctx)
+}
+
+// Identity returns the identity of this instance, the name of its pod
+func (l *LeaderElector) Identity() string {
+	return l.identity
+}
+
+// IsLeader checks if this instance holds the lease
+func (l *LeaderElector) IsLeader() bool {
+	return l.elector.IsLeader()
+}
+
+// ServeHTTP reports the identity of this instance and the current leader
+func (l *LeaderElector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"identity": l.identity,
+		"leader":   l.elector.GetLeader(),
+		"isLeader": l.elector.IsLeader(),
+	})
+}
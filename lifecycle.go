@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	vModel "dev.hackerman.me/artheon/veverse-shared/model"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"strings"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+// defaultStartTimeout is the time a game server pod has to become ready before the game server is set to error
+const defaultStartTimeout = 10 * time.Minute
+
+// container waiting reasons that will not resolve without changing the game server spec
+var podImageErrorReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// reasons of game server record status changes derived from the pod
+const (
+	lifecycleReasonImagePullFailed = "ImagePullFailed"
+	lifecycleReasonCrashed         = "Crashed"
+	lifecycleReasonExited          = "Exited"
+	lifecycleReasonStartTimeout    = "StartTimeout"
+)
+
+// podLifecycle is the game server record status derived from the pod, empty status means the pod does not require a record update
+type podLifecycle struct {
+	Status  string
+	Reason  string
+	Message string
+}
+
+// getPodLifecycle maps the state of the game server container to the game server record status, sidecars (e.g. the
+// probe sidecar) do not change the record status, their failures only make the pod unready
+func getPodLifecycle(pod *apiV1.Pod, containerName string) podLifecycle {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.Name != containerName {
+			continue
+		}
+
+		if waiting := containerStatus.State.Waiting; waiting != nil {
+			if podImageErrorReasons[waiting.Reason] {
+				return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonImagePullFailed, Message: fmt.Sprintf("failed to pull image %s: %s", containerStatus.Image, waiting.Message)}
+			}
+
+			if waiting.Reason == "CrashLoopBackOff" {
+				// report why the container crashed the last time
+				if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil {
+					if terminated.Reason == "Completed" {
+						return podLifecycle{Status: GameServerStatusOffline, Reason: lifecycleReasonExited, Message: "game server exited"}
+					}
+					if terminated.Reason == "OOMKilled" {
+						return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server has been killed for running out of memory"}
+					}
+					return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: fmt.Sprintf("game server is crash looping, last exit code %d: %s", terminated.ExitCode, terminated.Reason)}
+				}
+				return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server is crash looping"}
+			}
+		}
+
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			if terminated.Reason == "OOMKilled" {
+				return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server has been killed for running out of memory"}
+			}
+			if terminated.ExitCode == 0 {
+				return podLifecycle{Status: GameServerStatusOffline, Reason: lifecycleReasonExited, Message: "game server exited"}
+			}
+			return podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: fmt.Sprintf("game server exited with code %d: %s", terminated.ExitCode, terminated.Reason)}
+		}
+	}
+
+	// the game server binary marks itself online once ready, the operator only reports that it is starting
+	if ready := getPodReadyCondition(pod); ready == nil || ready.Status != apiV1.ConditionTrue {
+		return podLifecycle{Status: GameServerStatusStarting}
+	}
+
+	return podLifecycle{}
+}
+
+// reconcileGameServerLifecycle updates the game server record status from the game server pod, sets game servers which did
+// not become ready within the start timeout to error, returns the new record status or empty string if it did not change
+func reconcileGameServerLifecycle(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, gameServerRecord *vModel.GameServerV2, startTimeout time.Duration) (string, error) {
+	if gameServerRecord == nil || !isGameServerActive(gameServerRecord.Status) {
+		return "", nil
+	}
+
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	lifecycle := podLifecycle{}
+	if pod != nil {
+		lifecycle = getPodLifecycle(pod, getResourceName(id))
+	}
+
+	// time out game servers which never became ready, restarted pods get a new timeout
+	if lifecycle.Status == GameServerStatusStarting || pod == nil {
+		startedAt := gameServer.CreationTimestamp.Time
+		if pod != nil {
+			startedAt = pod.CreationTimestamp.Time
+		}
+
+		if gameServerRecord.Status != GameServerStatusOnline && time.Since(startedAt) > startTimeout {
+			lifecycle = podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonStartTimeout, Message: fmt.Sprintf("game server did not become ready within %v", startTimeout)}
+		}
+	}
+
+	switch lifecycle.Status {
+	case "":
+		return "", nil
+	case GameServerStatusStarting:
+		// never downgrade an online game server, it may be temporarily unready
+		if gameServerRecord.Status != GameServerStatusCreated {
+			return "", nil
+		}
+	}
+
+	if lifecycle.Status == gameServerRecord.Status {
+		return "", nil
+	}
+
+	if lifecycle.Status == GameServerStatusError || lifecycle.Status == GameServerStatusOffline {
+		Logger.Warningf("game server %s is %s: %s", id, lifecycle.Status, lifecycle.Message)
+	} else {
+		Logger.Infof("game server %s is %s", id, lifecycle.Status)
+	}
+
+	err = SetGameServerStatus(ctx, id, lifecycle.Status, lifecycle.Message)
+	if err != nil {
+		return "", err
+	}
+
+	recordGameServerLifecycleEvent(ctx, gameServer, lifecycle)
+	notifyGameServerLifecycle(ctx, id, gameServer, lifecycle)
+
+	return lifecycle.Status, nil
+}
+
+// recordGameServerLifecycleEvent records why the game server failed or went offline on the game server resource
+func recordGameServerLifecycleEvent(ctx context.Context, gameServer *veverseV1.GameServer, lifecycle podLifecycle) {
+	switch lifecycle.Reason {
+	case lifecycleReasonCrashed:
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonPodCrashLoop, "%s", lifecycle.Message)
+	case lifecycleReasonImagePullFailed:
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonImagePullFailed, "%s", lifecycle.Message)
+	case lifecycleReasonStartTimeout:
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeWarning, EventReasonStartTimeout, "%s", lifecycle.Message)
+	case lifecycleReasonExited:
+		recordGameServerEvent(ctx, gameServer, apiV1.EventTypeNormal, EventReasonMarkedOffline, "%s", lifecycle.Message)
+	}
+}
+
+// notifyGameServerLifecycle sends a notification for game servers which failed, starting and exited game servers are
+// not notified
+func notifyGameServerLifecycle(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, lifecycle podLifecycle) {
+	if lifecycle.Status != GameServerStatusError {
+		return
+	}
+
+	notification := Notification{
+		Event:        NotificationGameServerError,
+		Severity:     NotificationSeverityError,
+		Title:        "Game server failed",
+		Message:      lifecycle.Message,
+		GameServerId: id,
+		Fields:       gameServerNotificationFields(gameServer),
+	}
+
+	switch lifecycle.Reason {
+	case lifecycleReasonCrashed:
+		notification.Event = NotificationGameServerCrashed
+		notification.Title = "Game server crashed"
+	case lifecycleReasonStartTimeout:
+		notification.Event = NotificationGameServerTimedOut
+		notification.Severity = NotificationSeverityWarning
+		notification.Title = "Game server timed out"
+	}
+
+	notify(ctx, notification)
+}
+
+// getPodGameServerId returns the id of the game server owning the pod, uuid.Nil if the pod does not belong to a game server
+func getPodGameServerId(pod *apiV1.Pod) uuid.UUID {
+	if id := uuid.FromStringOrNil(pod.Labels[LabelGameServerId]); id != uuid.Nil {
+		return id
+	}
+
+	// pods created before the game server id label was introduced only have the app label
+	if app := pod.Labels["app"]; strings.HasPrefix(app, "gs-") {
+		return uuid.FromStringOrNil(strings.TrimPrefix(app, "gs-"))
+	}
+
+	return uuid.Nil
+}
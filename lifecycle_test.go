@@ -0,0 +1,129 @@
+package main
+
+import (
+	apiV1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func TestGetPodLifecycle(t *testing.T) {
+	const containerName = "gs-2f4a6c5e-8b1d-4e3f-9a7b-0c1d2e3f4a5b"
+
+	running := apiV1.ContainerState{Running: &apiV1.ContainerStateRunning{}}
+	crashLoop := apiV1.ContainerState{Waiting: &apiV1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}
+
+	tests := []struct {
+		name       string
+		containers []apiV1.ContainerStatus
+		ready      apiV1.ConditionStatus
+		want       podLifecycle
+	}{
+		{
+			name:       "ready",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: running}},
+			ready:      apiV1.ConditionTrue,
+			want:       podLifecycle{},
+		},
+		{
+			name:       "not ready",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: running}},
+			ready:      apiV1.ConditionFalse,
+			want:       podLifecycle{Status: GameServerStatusStarting},
+		},
+		{
+			name:       "no ready condition",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: running}},
+			want:       podLifecycle{Status: GameServerStatusStarting},
+		},
+		{
+			name: "image pull failed",
+			containers: []apiV1.ContainerStatus{{Name: containerName, Image: "veverse/server:1.0.0", State: apiV1.ContainerState{
+				Waiting: &apiV1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "not found"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonImagePullFailed, Message: "failed to pull image veverse/server:1.0.0: not found"},
+		},
+		{
+			name: "exited",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: apiV1.ContainerState{
+				Terminated: &apiV1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusOffline, Reason: lifecycleReasonExited, Message: "game server exited"},
+		},
+		{
+			name: "crashed",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: apiV1.ContainerState{
+				Terminated: &apiV1.ContainerStateTerminated{ExitCode: 139, Reason: "Error"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server exited with code 139: Error"},
+		},
+		{
+			name: "out of memory",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: apiV1.ContainerState{
+				Terminated: &apiV1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server has been killed for running out of memory"},
+		},
+		{
+			name: "crash loop after exit",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: crashLoop, LastTerminationState: apiV1.ContainerState{
+				Terminated: &apiV1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusOffline, Reason: lifecycleReasonExited, Message: "game server exited"},
+		},
+		{
+			name: "crash loop after crash",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: crashLoop, LastTerminationState: apiV1.ContainerState{
+				Terminated: &apiV1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"},
+			}}},
+			want: podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server is crash looping, last exit code 1: Error"},
+		},
+		{
+			name:       "crash loop without last state",
+			containers: []apiV1.ContainerStatus{{Name: containerName, State: crashLoop}},
+			want:       podLifecycle{Status: GameServerStatusError, Reason: lifecycleReasonCrashed, Message: "game server is crash looping"},
+		},
+		{
+			name: "sidecar crash loop after exit",
+			containers: []apiV1.ContainerStatus{
+				{Name: containerName, State: running},
+				{Name: "probe", State: crashLoop, LastTerminationState: apiV1.ContainerState{
+					Terminated: &apiV1.ContainerStateTerminated{ExitCode: 0, Reason: "Completed"},
+				}},
+			},
+			ready: apiV1.ConditionFalse,
+			want:  podLifecycle{Status: GameServerStatusStarting},
+		},
+		{
+			name: "sidecar out of memory",
+			containers: []apiV1.ContainerStatus{
+				{Name: "probe", State: apiV1.ContainerState{
+					Terminated: &apiV1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"},
+				}},
+				{Name: containerName, State: running},
+			},
+			ready: apiV1.ConditionTrue,
+			want:  podLifecycle{},
+		},
+		{
+			name: "sidecar image pull failed",
+			containers: []apiV1.ContainerStatus{
+				{Name: containerName, State: running},
+				{Name: "probe", State: apiV1.ContainerState{Waiting: &apiV1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+			},
+			ready: apiV1.ConditionFalse,
+			want:  podLifecycle{Status: GameServerStatusStarting},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &apiV1.Pod{Status: apiV1.PodStatus{ContainerStatuses: test.containers}}
+			if test.ready != "" {
+				pod.Status.Conditions = []apiV1.PodCondition{{Type: apiV1.PodReady, Status: test.ready}}
+			}
+
+			if got := getPodLifecycle(pod, containerName); got != test.want {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -4,16 +4,21 @@ import (
 	"context"
 	"fmt"
 	"github.com/gofrs/uuid"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/dynamic/dynamicinformer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+	"veverse-server-operator/pkg/client/informers/externalversions"
 )
 
 const (
@@ -47,31 +52,107 @@ func getResourceName(id uuid.UUID) string {
 
 var updateInterval = 60 * time.Second
 
-func main() {
-	// algorithm
-	// 1. watch create and delete events for gameserver resources
-	// 2. get all gameserver, deployment and service resources
-	// 3. get all gameserver records
-	// 4. check if gameserver record has a matching resources and create them as required
-	// 5. update the game server metadata with the service node port
+// getEnvDuration parses a duration (e.g. "90s") or a number of seconds from the env variable, returns the default value if
+// the variable is not set, invalid or zero
+func getEnvDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
 
-	// get update interval from env or use default value of 60 seconds
-	if updateIntervalEnv := os.Getenv("UPDATE_INTERVAL"); updateIntervalEnv != "" {
-		parsedInterval, err := time.ParseDuration(updateIntervalEnv)
-		if err != nil {
-			updateInterval = parsedInterval
-		} else {
-			parsedInterval, err := strconv.Atoi(updateIntervalEnv)
-			if err != nil {
-				updateInterval = time.Duration(parsedInterval) * time.Second
-			}
-		}
+	var duration time.Duration
+	if parsedDuration, err := time.ParseDuration(value); err == nil {
+		duration = parsedDuration
+	} else if parsedSeconds, err := strconv.Atoi(value); err == nil {
+		duration = time.Duration(parsedSeconds) * time.Second
+	} else {
+		Logger.Warningf("invalid %s value %q, using default %v", name, value, defaultValue)
+	}
+
+	if duration <= 0 {
+		return defaultValue
+	}
+
+	return duration
+}
+
+// getEnv returns the env variable or the default value if it is not set
+func getEnv(name string, defaultValue string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+
+	return defaultValue
+}
+
+// getEnvBool parses a boolean env variable, returns the default value if the variable is not set or invalid
+func getEnvBool(name string, defaultValue bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsedValue, err := strconv.ParseBool(value)
+	if err != nil {
+		Logger.Warningf("invalid %s value %q, using default %v", name, value, defaultValue)
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+// getEnvInt parses an integer env variable, returns the default value if the variable is not set, invalid or not positive
+func getEnvInt(name string, defaultValue int64) int64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
 
-		if updateInterval == 0 {
-			updateInterval = 60 * time.Second
+	parsedValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsedValue <= 0 {
+		Logger.Warningf("invalid %s value %q, using default %v", name, value, defaultValue)
+		return defaultValue
+	}
+
+	return parsedValue
+}
+
+// getEnvList splits a comma separated env variable, empty items are skipped
+func getEnvList(name string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
 		}
 	}
 
+	return values
+}
+
+// controllerWorkers is the number of game servers reconciled concurrently
+const controllerWorkers = 2
+
+func main() {
+	// algorithm
+	// 0. campaign for the operator lease, only the leader runs the following steps, except for serving the allocation endpoint
+	// 1. watch create, update and delete events for gameserver resources and queue them for reconciliation
+	// 2. periodically queue all active gameserver records
+	// 3. reconcile each queued game server: create missing deployments and services, delete resources of finished game servers
+	//    and mark records without a matching game server resource offline, drain deleted game servers before tearing them down
+	// 4. update the game server record with the service node port and the status derived from the game server pod, set
+	//    game servers that failed or did not become ready in time to error
+	// 5. retry failed reconciles with exponential backoff and requeue successful ones after the update interval
+	// 6. periodically delete operator managed deployments and services that do not match any game server resource
+	// 7. reconcile game server fleets into game server resources and records, replace game servers running an outdated
+	//    release and scale fleets to their replicas
+	// 8. periodically scale fleets with a fleet autoscaler from the occupancy of their game servers
+	// 9. serve the allocation endpoint, which reserves player slots on ready game servers
+	// 10. notify chat services about game server lifecycle events, orphan cleanups and operator restarts
+	// 11. serve the admission webhooks setting the defaults of game server specs and rejecting invalid ones
+
+	// get update interval from env or use default value of 60 seconds
+	updateInterval = getEnvDuration("UPDATE_INTERVAL", updateInterval)
+
 	Logger.Infof("update interval: %v", updateInterval)
 
 	// create a context, contains database connection, kubernetes clientset, game server resource definition, and config
@@ -125,193 +206,216 @@ func main() {
 
 	ctx = context.WithValue(ctx, "clientset", clientset)
 
-	// create a dynamic client using the config
-	dynamicClient, err := dynamic.NewForConfig(config)
+	// create a typed clientset for the veverse.com custom resources
+	veverseClientset, err := versioned.NewForConfig(config)
 	if err != nil {
-		Logger.Fatalf("failed to create a clientset: %v", err)
+		Logger.Fatalf("failed to create a veverse clientset: %v", err)
 	}
 
-	ctx = context.WithValue(ctx, "dynamicClient", dynamicClient)
+	ctx = context.WithValue(ctx, "veverseClientset", veverseClientset)
+
+	// record events on game server resources, so they are shown by kubectl describe
+	recorder, eventBroadcaster, err := NewEventRecorder(ctx)
+	if err != nil {
+		Logger.Fatalf("failed to create an event recorder: %v", err)
+	}
+	defer eventBroadcaster.Shutdown()
+
+	ctx = context.WithValue(ctx, "recorder", recorder)
 
 	//endregion
 
-	gameServerResource := schema.GroupVersionResource{Group: "veverse.com", Version: "v1", Resource: "gameservers"}
+	// create an informer for the gameserver resource
+	fac := externalversions.NewSharedInformerFactoryWithOptions(veverseClientset, 0, externalversions.WithNamespace(namespace))
+	gameServerInformer := fac.Veverse().V1().GameServers()
+	fleetInformer := fac.Veverse().V1().GameServerFleets()
+	autoscalerInformer := fac.Veverse().V1().FleetAutoscalers()
 
-	ctx = context.WithValue(ctx, "gameServerResource", gameServerResource)
+	// create an informer for the game server pods
+	kubeFac := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	podInformer := kubeFac.Core().V1().Pods()
+	// services are only watched for the node port usage metrics
+	serviceInformer := kubeFac.Core().V1().Services()
 
-	// create a informer for the gameserver resource
-	fac := dynamicinformer.NewFilteredDynamicSharedInformerFactory(dynamicClient, 0, namespace, nil)
-	informer := fac.ForResource(gameServerResource).Informer()
+	ctx = context.WithValue(ctx, "podLister", podInformer.Lister())
 
-	// add event handlers
-	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		// handle new gameserver resources added to the cluster
-		AddFunc: func(obj interface{}) {
-			Logger.Infof("add event: %+v", obj)
+	// nodes are cluster scoped, they are watched for the addresses and the number of nodes sharing host ports
+	nodeFac := informers.NewSharedInformerFactory(clientset, 0)
+	nodeInformer := nodeFac.Core().V1().Nodes()
 
-			gameServerMetadata, ok := obj.(*unstructured.Unstructured)
-			if !ok {
-				Logger.Errorf("failed to convert to unstructured: %v", obj)
-				return
-			}
+	ctx = context.WithValue(ctx, "nodeLister", nodeInformer.Lister())
 
-			err := createGameServerDeploymentClusterResource(ctx, *gameServerMetadata)
-			if err != nil {
-				Logger.Errorf("failed to create deployment: %v", err)
-				return
-			}
+	probeSidecarImage = os.Getenv("GAME_SERVER_PROBE_SIDECAR_IMAGE")
+	defaultApiCredentialsSecret = os.Getenv("API_CREDENTIALS_SECRET")
 
-			err = createGameServerServiceClusterResource(ctx, *gameServerMetadata)
-			if err != nil {
-				Logger.Errorf("failed to create service: %v", err)
-				return
-			}
-		},
-		UpdateFunc: func(oldObj, newObj interface{}) {
-			Logger.Infof("update event: %+v", newObj)
-		},
-		// handle gameserver resources removed from the cluster
-		DeleteFunc: func(obj interface{}) {
-			Logger.Infof("delete event: %+v", obj)
-
-			gameServerMetadata, ok := obj.(*unstructured.Unstructured)
-			if !ok {
-				Logger.Errorf("failed to convert to unstructured: %v", obj)
-				return
-			}
+	// game server api tokens are signed with the operator private key
+	if getEnvBool("GAME_SERVER_TOKENS_ENABLED", false) {
+		gameServerTokens, err = NewTokenIssuer(os.Getenv("PRIVATE_KEY"), getEnvDuration("GAME_SERVER_TOKEN_TTL", defaultGameServerTokenTtl), getEnvDuration("GAME_SERVER_TOKEN_ROTATE_BEFORE", defaultGameServerTokenRotateBefore))
+		if err != nil {
+			Logger.Fatalf("failed to setup game server tokens: %v", err)
+		}
+	}
 
-			id, err := uuid.FromString(gameServerMetadata.GetName())
-			if err != nil {
-				Logger.Errorf("failed to parse id: %v", err)
-				return
-			}
+	// game servers are exposed with node port services unless configured otherwise
+	defaultExposureType, err = parseExposureType(os.Getenv("GAME_SERVER_EXPOSURE"))
+	if err != nil {
+		Logger.Fatalf("failed to parse game server exposure: %v", err)
+	}
+	loadBalancerAnnotations, err = parseAnnotations(getEnvList("GAME_SERVER_LOAD_BALANCER_ANNOTATIONS"))
+	if err != nil {
+		Logger.Fatalf("failed to parse load balancer annotations: %v", err)
+	}
+	hostPorts, err = NewHostPortAllocator(nodeInformer.Lister(), gameServerInformer.Lister(), getEnv("GAME_SERVER_HOST_PORT_RANGE", defaultHostPortRange))
+	if err != nil {
+		Logger.Fatalf("failed to setup host port allocator: %v", err)
+	}
 
-			err = deleteGameServerDeploymentClusterResource(ctx, id)
-			if err != nil {
-				Logger.Errorf("failed to delete deployment: %v", err)
-				return
-			}
+	apiCredentialsMigration, err = parseApiCredentialsMigration(os.Getenv("API_CREDENTIALS_MIGRATION"))
+	if err != nil {
+		Logger.Fatalf("failed to parse api credentials migration: %v", err)
+	}
+	gameServerDrainTimeout = getEnvDuration("GAME_SERVER_DRAIN_TIMEOUT", defaultDrainTimeout)
 
-			err = deleteGameServerServiceClusterResource(ctx, id)
+	// load operator wide resources and node placement of game servers
+	if schedulingDefaultsFile := os.Getenv("GAME_SERVER_SCHEDULING_FILE"); schedulingDefaultsFile != "" {
+		schedulingDefaults, err = loadSchedulingDefaults(schedulingDefaultsFile)
+		if err != nil {
+			Logger.Fatalf("failed to load scheduling defaults: %v", err)
+		}
+	}
+
+	startTimeout := getEnvDuration("GAME_SERVER_START_TIMEOUT", defaultStartTimeout)
+	Logger.Infof("game server start timeout: %v", startTimeout)
+
+	controller, err := NewController(gameServerInformer, podInformer, updateInterval, startTimeout)
+	if err != nil {
+		Logger.Fatalf("failed to create controller: %v", err)
+	}
+
+	fleetController, err := NewFleetController(fleetInformer, gameServerInformer, updateInterval)
+	if err != nil {
+		Logger.Fatalf("failed to create fleet controller: %v", err)
+	}
+
+	//region Notifications
+
+	notificationEvents, err := parseNotificationEvents(os.Getenv("NOTIFY_EVENTS"))
+	if err != nil {
+		Logger.Fatalf("failed to parse notification events: %v", err)
+	}
+
+	notifier := NewNotifier(notificationEvents, getEnvDuration("NOTIFY_BATCH_INTERVAL", defaultNotificationBatchInterval))
+	if discordHookUrl := os.Getenv("DISCORD_HOOK_URL"); discordHookUrl != "" {
+		notifier.AddSink(NewDiscordSink(discordHookUrl), discordRateLimit, discordRateBurst)
+	}
+
+	ctx = context.WithValue(ctx, "notifier", notifier)
+
+	//endregion
+
+	// stop the informers and workers on termination
+	ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// pending notifications are sent before the operator exits
+	notifierDone := make(chan struct{})
+	go func() {
+		notifier.Run(ctx)
+		close(notifierDone)
+	}()
+
+	fac.Start(ctx.Done())
+	kubeFac.Start(ctx.Done())
+	nodeFac.Start(ctx.Done())
+
+	// periodically delete deployments and services left behind without a game server
+	sweeper := NewSweeper(gameServerInformer, getEnvDuration("ORPHAN_SWEEP_INTERVAL", updateInterval), getEnvDuration("ORPHAN_GRACE_PERIOD", defaultOrphanGracePeriod), getEnvBool("ORPHAN_SWEEP_DRY_RUN", false))
+
+	// periodically scale fleets to keep a buffer of free player slots
+	autoscaler := NewAutoscaler(autoscalerInformer, fleetInformer, gameServerInformer, getEnvDuration("FLEET_AUTOSCALER_INTERVAL", defaultAutoscalerInterval))
+
+	// only the leader reconciles, the informer caches of the other instances are kept warm for a fast failover
+	leaderElector, err := NewLeaderElector(ctx, func(ctx context.Context) {
+		go sweeper.Run(ctx)
+		go autoscaler.Run(ctx)
+
+		go func() {
+			err := fleetController.Run(ctx, fleetWorkers)
 			if err != nil {
-				Logger.Errorf("failed to delete service: %v", err)
-				return
+				Logger.Errorf("failed to run fleet controller: %v", err)
 			}
-		},
+		}()
+
+		err := controller.Run(ctx, controllerWorkers)
+		if err != nil {
+			Logger.Errorf("failed to run controller: %v", err)
+		}
 	})
 	if err != nil {
-		Logger.Fatalf("failed to add event handler: %v", err)
+		Logger.Fatalf("failed to setup leader election: %v", err)
 	}
 
-	// get all current game server resources and create deployments and services for them if they don't exist
-	for {
-		gameServerRecords, err := GetOnlineGameServers(ctx)
+	notifier.Notify(Notification{
+		Event:   NotificationOperatorStarted,
+		Title:   "Operator started",
+		Message: fmt.Sprintf("Operator instance %s started.", leaderElector.Identity()),
+	})
+
+	// expose the game server and node port metrics read on each scrape next to the metrics updated by the reconcile loops
+	collector, err := NewCollector(ctx, serviceInformer.Lister(), getEnv("NODE_PORT_RANGE", defaultNodePortRange))
+	if err != nil {
+		Logger.Fatalf("failed to create metrics collector: %v", err)
+	}
+	prometheus.MustRegister(collector)
+
+	// serve the allocation endpoint, the leader status, the metrics and the health checks on every instance
+	mux := http.NewServeMux()
+	mux.Handle("/allocate", NewAllocator(gameServerInformer.Lister(), getEnvDuration("ALLOCATION_TTL", defaultAllocationTtl), os.Getenv("ALLOCATOR_TOKEN")))
+	mux.Handle("/leader", leaderElector)
+	mux.Handle("/metrics", promhttp.Handler())
+
+	health := NewHealth(map[string]cache.InformerSynced{
+		"gameservers":      gameServerInformer.Informer().HasSynced,
+		"gameserverfleets": fleetInformer.Informer().HasSynced,
+		"fleetautoscalers": autoscalerInformer.Informer().HasSynced,
+		"pods":             podInformer.Informer().HasSynced,
+		"services":         serviceInformer.Informer().HasSynced,
+		"nodes":            nodeInformer.Informer().HasSynced,
+	}, controller, leaderElector, getEnvDuration("HEALTH_MAX_RECONCILE_AGE", 5*updateInterval), getEnvDuration("HEALTH_INFORMER_SYNC_TIMEOUT", defaultInformerSyncTimeout))
+	mux.HandleFunc("/healthz", health.ServeLiveness)
+	mux.HandleFunc("/readyz", health.ServeReadiness)
+	go runHttpServer(ctx, getEnv("HTTP_ADDRESS", ":8000"), mux)
+
+	// the admission webhook is served with TLS on a separate address, it is disabled if the address is not set
+	if webhookAddress := os.Getenv("WEBHOOK_ADDRESS"); webhookAddress != "" {
+		certificate, err := newWebhookCertificate(ctx)
 		if err != nil {
-			Logger.Errorf("failed to get active game servers: %v", err)
-			continue
+			Logger.Fatalf("failed to setup webhook certificate: %v", err)
 		}
 
-		// check for matching deployments and services which need to be created or deleted if the game server resource is offline or in error state and were not handled by the event handler for some reason
-		for _, gameServerRecord := range gameServerRecords.Entities {
-			if gameServerRecord.Status == GameServerStatusOffline || gameServerRecord.Status == GameServerStatusError {
-				// delete offline and error game servers
-				gameServer, err := getGameServerClusterResource(ctx, gameServerRecord.Id)
-				if err != nil {
-					Logger.Errorf("failed to get game server: %v", err)
-					continue
-				}
-				if gameServer == nil {
-					err := deleteGameServerClusterResource(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to delete game server: %v", err)
-						continue
-					}
-				}
-
-				// delete deployment if it still exists
-				deployment, err := getGameServerDeploymentClusterResource(ctx, gameServerRecord.Id)
-				if err != nil {
-					Logger.Errorf("failed to get deployment: %v", err)
-					continue
-				}
-				if deployment != nil {
-					err := deleteGameServerDeploymentClusterResource(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to delete deployment: %v", err)
-						continue
-					}
-				}
-
-				// delete service if it still exists
-				service, err := getGameServerServiceClusterResource(ctx, gameServerRecord.Id)
-				if err != nil {
-					Logger.Errorf("failed to get service: %v", err)
-					continue
-				}
-				if service != nil {
-					err := deleteGameServerServiceClusterResource(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to delete service: %v", err)
-						continue
-					}
-				}
-			} else if gameServerRecord.Status == GameServerStatusOnline || gameServerRecord.Status == GameServerStatusStarting || gameServerRecord.Status == GameServerStatusCreated {
-				// if there is no deployment, but we have an active game server record, mark the game server as offline and delete the matching service to release node ports
-				deployment, err := getGameServerDeploymentClusterResource(ctx, gameServerRecord.Id)
-				if err != nil {
-					Logger.Errorf("failed to get deployment: %v", err)
-					continue
-				}
-				if deployment == nil {
-					Logger.Warningf("deployment not found for game server: %v, marking server as offline", gameServerRecord.Id)
-
-					err = SetGameServerOffline(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to set game server offline: %v", err)
-						continue
-					}
-
-					// check if there is a service for the game server and delete it
-					service, err := getGameServerServiceClusterResource(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to get service: %v", err)
-						continue
-					}
-
-					if service != nil {
-						err = deleteGameServerServiceClusterResource(ctx, gameServerRecord.Id)
-						if err != nil {
-							Logger.Errorf("failed to delete service: %v", err)
-							continue
-						}
-					}
-
-					continue
-				}
-
-				// if there is no service, but server exists, try to create a service for it
-				service, err := getGameServerServiceClusterResource(ctx, gameServerRecord.Id)
-				if err != nil {
-					Logger.Errorf("failed to get service: %v", err)
-					continue
-				}
-				if service == nil {
-					port, err := createGameServerServiceClusterResourceWithId(ctx, gameServerRecord.Id)
-					if err != nil {
-						Logger.Errorf("failed to create service: %v", err)
-						continue
-					}
-
-					// update the game server record with the port
-					err = SetGameServerPort(ctx, gameServerRecord.Id, port)
-					if err != nil {
-						Logger.Errorf("failed to set game server port: %v", err)
-						continue
-					}
-				}
+		// the game server spec defaults are resolved by the mutating webhook before the spec is validated
+		gameServerDefaults := &GameServerDefaults{}
+		if gameServerDefaultsFile := os.Getenv("GAME_SERVER_DEFAULTS_FILE"); gameServerDefaultsFile != "" {
+			gameServerDefaults, err = loadGameServerDefaults(gameServerDefaultsFile)
+			if err != nil {
+				Logger.Fatalf("failed to load game server defaults: %v", err)
 			}
 		}
 
-		time.Sleep(updateInterval)
+		validator := NewGameServerValidator(getEnvList("WEBHOOK_ALLOWED_REGISTRIES"), getEnvInt("GAME_SERVER_MAX_PLAYERS_LIMIT", defaultMaxPlayersLimit))
+
+		webhookMux := http.NewServeMux()
+		webhookMux.Handle("/mutate-gameserver", NewMutatingWebhook(NewGameServerDefaulter(gameServerDefaults)))
+		webhookMux.Handle("/validate-gameserver", NewValidatingWebhook(validator))
+		go runHttpsServer(ctx, webhookAddress, webhookMux, certificate)
 	}
+
+	// returns on termination or when the leadership is lost, in which case the operator exits and is restarted by the cluster
+	leaderElector.Run(ctx)
+	if ctx.Err() == nil {
+		Logger.Errorf("lost leadership, exiting")
+	}
+
+	cancel()
+	<-notifierDone
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/labels"
+	coreListers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	metricsNamespace = "veverse"
+
+	// defaultNodePortRange is the default service node port range of the kube-apiserver
+	defaultNodePortRange = "30000-32767"
+
+	// metricsCollectTimeout is the timeout of the database query run on each scrape
+	metricsCollectTimeout = 5 * time.Second
+)
+
+var (
+	reconcileTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_total",
+		Help:      "Number of reconciles by operation and result.",
+	}, []string{"operation", "result"})
+
+	reconcileDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_duration_seconds",
+		Help:      "Duration of reconciles by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	gameServerTimeToOnline = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "game_server_time_to_online_seconds",
+		Help:      "Time from the creation of the game server resource until the game server reported itself online.",
+		Buckets:   prometheus.ExponentialBuckets(5, 2, 10),
+	})
+
+	databaseQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "database_query_duration_seconds",
+		Help:      "Duration of database queries by query.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query"})
+
+	kubernetesApiErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "kubernetes_api_errors_total",
+		Help:      "Number of failed Kubernetes API requests by method and status code, <error> if the request did not get a response.",
+	}, []string{"method", "code"})
+
+	orphansDeletedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphans_deleted_total",
+		Help:      "Number of deleted orphaned resources by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	// counts the results of all requests of the rest clients, the clients report them once the metrics are registered
+	metrics.Register(metrics.RegisterOpts{RequestResult: kubernetesRequestResult{}})
+}
+
+// observeReconcile records the result and duration of a reconcile of the operation started at start
+func observeReconcile(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	reconcileTotal.WithLabelValues(operation, result).Inc()
+	reconcileDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// observeDatabaseQuery records the duration of the query started at start, called deferred by the database functions
+func observeDatabaseQuery(query string, start time.Time) {
+	databaseQueryDuration.WithLabelValues(query).Observe(time.Since(start).Seconds())
+}
+
+// kubernetesRequestResult counts Kubernetes API requests which did not succeed
+type kubernetesRequestResult struct{}
+
+func (kubernetesRequestResult) Increment(_ context.Context, code string, method string, _ string) {
+	if strings.HasPrefix(code, "2") {
+		return
+	}
+
+	kubernetesApiErrorsTotal.WithLabelValues(method, code).Inc()
+}
+
+// Collector exposes the metrics which are read on each scrape: the game servers by status from the database and the
+// node port pool usage of the game server services
+type Collector struct {
+	ctx           context.Context
+	serviceLister coreListers.ServiceLister
+	nodePortRange int
+
+	gameServers     *prometheus.Desc
+	nodePortsUsed   *prometheus.Desc
+	nodePortsTotal  *prometheus.Desc
+	collectorErrors *prometheus.Desc
+}
+
+// NewCollector creates a collector, the node port range is formatted like the --service-node-port-range of the kube-apiserver
+func NewCollector(ctx context.Context, serviceLister coreListers.ServiceLister, nodePortRange string) (*Collector, error) {
+	size, err := parsePortRangeSize(nodePortRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node port range %s: %v", nodePortRange, err)
+	}
+
+	return &Collector{
+		ctx:           ctx,
+		serviceLister: serviceLister,
+		nodePortRange: size,
+		gameServers: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "game_servers"),
+			"Number of game server records by status.", []string{"status"}, nil),
+		nodePortsUsed: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "node_ports_used"),
+			"Number of node ports allocated to services in the operator namespace.", nil, nil),
+		nodePortsTotal: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "node_ports_total"),
+			"Size of the node port range.", nil, nil),
+		collectorErrors: prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", "collector_errors"),
+			"1 if the last scrape failed to collect a metric source.", []string{"source"}, nil),
+	}, nil
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.gameServers
+	ch <- c.nodePortsUsed
+	ch <- c.nodePortsTotal
+	ch <- c.collectorErrors
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(c.ctx, metricsCollectTimeout)
+	defer cancel()
+
+	databaseError := 0.0
+	counts, err := GetGameServerStatusCounts(ctx)
+	if err != nil {
+		Logger.Warningf("failed to collect game server metrics: %v", err)
+		databaseError = 1
+	}
+	for status, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.gameServers, prometheus.GaugeValue, float64(count), status)
+	}
+	ch <- prometheus.MustNewConstMetric(c.collectorErrors, prometheus.GaugeValue, databaseError, "database")
+
+	servicesError := 0.0
+	services, err := c.serviceLister.Services(c.ctx.Value("namespace").(string)).List(labels.Everything())
+	if err != nil {
+		Logger.Warningf("failed to collect node port metrics: %v", err)
+		servicesError = 1
+	}
+	used := 0
+	for _, service := range services {
+		for _, port := range service.Spec.Ports {
+			if port.NodePort > 0 {
+				used++
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.nodePortsUsed, prometheus.GaugeValue, float64(used))
+	ch <- prometheus.MustNewConstMetric(c.nodePortsTotal, prometheus.GaugeValue, float64(c.nodePortRange))
+	ch <- prometheus.MustNewConstMetric(c.collectorErrors, prometheus.GaugeValue, servicesError, "services")
+}
+
+// parsePortRangeSize returns the number of ports in a range formatted as first-last
+func parsePortRangeSize(portRange string) (int, error) {
+	first, last, err := parsePortRange(portRange)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(last - first + 1), nil
+}
+
+// parsePortRange returns the first and last port of a range formatted as first-last
+func parsePortRange(portRange string) (int32, int32, error) {
+	first, last, ok := strings.Cut(portRange, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected first-last")
+	}
+
+	firstPort, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lastPort, err := strconv.Atoi(strings.TrimSpace(last))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if firstPort <= 0 || lastPort < firstPort || lastPort > 65535 {
+		return 0, 0, fmt.Errorf("ports must be ascending between 1 and 65535")
+	}
+
+	return int32(firstPort), int32(lastPort), nil
+}
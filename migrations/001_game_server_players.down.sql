@@ -0,0 +1 @@
+alter table game_server_v2 drop column if exists players;
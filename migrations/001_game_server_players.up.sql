@@ -0,0 +1,2 @@
+-- number of players currently connected to the game server, reported by the game server binary with its status updates
+alter table game_server_v2 add column if not exists players integer not null default 0;
@@ -0,0 +1 @@
+drop table if exists fleet_autoscaler_decisions;
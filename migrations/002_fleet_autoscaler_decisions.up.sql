@@ -0,0 +1,18 @@
+-- decisions of the fleet autoscalers, kept to audit why fleets have been scaled
+create table if not exists fleet_autoscaler_decisions
+(
+    id               bigserial primary key,
+    created_at       timestamptz not null default now(),
+    namespace        text        not null,
+    autoscaler       text        not null,
+    fleet            text        not null,
+    current_replicas integer     not null,
+    desired_replicas integer     not null,
+    players          bigint      not null,
+    capacity         bigint      not null,
+    schedule         text,
+    applied          boolean     not null,
+    reason           text        not null
+);
+
+create index if not exists fleet_autoscaler_decisions_autoscaler_idx on fleet_autoscaler_decisions (namespace, autoscaler, created_at);
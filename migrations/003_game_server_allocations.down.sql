@@ -0,0 +1,2 @@
+alter table game_server_v2 drop column if exists allocated_at;
+alter table game_server_v2 drop column if exists allocated_players;
@@ -0,0 +1,3 @@
+-- player slots reserved by the operator allocation endpoint, reservations expire once the players had time to connect
+alter table game_server_v2 add column if not exists allocated_players integer not null default 0;
+alter table game_server_v2 add column if not exists allocated_at timestamptz;
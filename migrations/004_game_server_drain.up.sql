@@ -0,0 +1,2 @@
+-- time when the operator started draining the game server, draining game servers are not allocated
+alter table game_server_v2 add column if not exists drain_started_at timestamptz;
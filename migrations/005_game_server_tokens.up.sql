@@ -0,0 +1,11 @@
+-- api tokens minted by the operator for each game server, the api rejects tokens which are revoked or expired
+create table if not exists game_server_tokens
+(
+    id             uuid primary key,
+    game_server_id uuid        not null,
+    issued_at      timestamptz not null default now(),
+    expires_at     timestamptz not null,
+    revoked_at     timestamptz
+);
+
+create index if not exists game_server_tokens_game_server_idx on game_server_tokens (game_server_id) where revoked_at is null;
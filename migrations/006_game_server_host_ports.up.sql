@@ -0,0 +1,11 @@
+-- host ports allocated by the operator to game servers exposed with host ports, the node is set once the game server
+-- pod has been scheduled, a port is used by a single game server on each node
+create table if not exists game_server_host_ports
+(
+    game_server_id uuid primary key,
+    port           integer     not null,
+    node_name      text,
+    allocated_at   timestamptz not null default now()
+);
+
+create unique index if not exists game_server_host_ports_node_port_idx on game_server_host_ports (node_name, port) where node_name is not null;
@@ -0,0 +1,6 @@
+drop table if exists game_server_ports;
+
+delete from game_server_host_ports where port_name <> 'unreal';
+alter table game_server_host_ports drop constraint if exists game_server_host_ports_pkey;
+alter table game_server_host_ports add primary key (game_server_id);
+alter table game_server_host_ports drop column if exists port_name;
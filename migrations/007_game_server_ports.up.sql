@@ -0,0 +1,18 @@
+-- game servers have several named ports, each public port exposed with host ports gets its own host port
+alter table game_server_host_ports add column if not exists port_name text not null default 'unreal';
+alter table game_server_host_ports drop constraint if exists game_server_host_ports_pkey;
+alter table game_server_host_ports add primary key (game_server_id, port_name);
+
+-- ports of the game servers and where they are reached, the unreal server port is also stored in game_server_v2, so
+-- clients can discover the other ports, e.g. the beacon, query or voice chat port
+create table if not exists game_server_ports
+(
+    game_server_id uuid    not null,
+    name           text    not null,
+    protocol       text    not null,
+    container_port integer not null,
+    policy         text    not null,
+    host           text,
+    port           integer,
+    primary key (game_server_id, name)
+);
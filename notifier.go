@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"golang.org/x/time/rate"
+	"strings"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+// NotificationEvent is the kind of event a notification reports
+type NotificationEvent string
+
+const (
+	NotificationGameServerCreated  NotificationEvent = "game_server_created"
+	NotificationGameServerOnline   NotificationEvent = "game_server_online"
+	NotificationGameServerCrashed  NotificationEvent = "game_server_crashed"
+	NotificationGameServerTimedOut NotificationEvent = "game_server_timed_out"
+	NotificationGameServerError    NotificationEvent = "game_server_error"
+	NotificationOrphanDeleted      NotificationEvent = "orphan_deleted"
+	NotificationOperatorStarted    NotificationEvent = "operator_started"
+)
+
+// notificationEvents are all events, enabled unless NOTIFY_EVENTS lists a subset
+var notificationEvents = []NotificationEvent{
+	NotificationGameServerCreated,
+	NotificationGameServerOnline,
+	NotificationGameServerCrashed,
+	NotificationGameServerTimedOut,
+	NotificationGameServerError,
+	NotificationOrphanDeleted,
+	NotificationOperatorStarted,
+}
+
+// NotificationSeverity is used by sinks to highlight notifications
+type NotificationSeverity string
+
+const (
+	NotificationSeverityInfo    NotificationSeverity = "info"
+	NotificationSeverityWarning NotificationSeverity = "warning"
+	NotificationSeverityError   NotificationSeverity = "error"
+)
+
+const (
+	// defaultNotificationBatchInterval is the time notifications are collected before they are sent together
+	defaultNotificationBatchInterval = 5 * time.Second
+	// notificationQueueSize is the number of pending notifications per sink, further notifications are dropped
+	notificationQueueSize = 256
+	// notificationFlushTimeout is the time pending notifications have to be sent when the operator stops
+	notificationFlushTimeout = 5 * time.Second
+)
+
+// Notification is a game server lifecycle or operator event sent to chat services
+type Notification struct {
+	Event        NotificationEvent
+	Severity     NotificationSeverity
+	Title        string
+	Message      string
+	GameServerId uuid.UUID
+	Fields       []NotificationField
+	Time         time.Time
+}
+
+// NotificationField is an additional named value of a notification
+type NotificationField struct {
+	Name  string
+	Value string
+}
+
+// NotificationSink delivers notifications to a chat service or webhook, implementations are added to the notifier with
+// their rate limit
+type NotificationSink interface {
+	// Name identifies the sink in logs
+	Name() string
+	// MaxBatchSize is the maximum number of notifications sent with a single Send call
+	MaxBatchSize() int
+	// Send delivers the notifications, it is not retried if it fails
+	Send(ctx context.Context, notifications []Notification) error
+}
+
+// Notifier collects notifications and sends them to the sinks in batches, each sink has its own queue and rate limit, so
+// a slow or failing sink does not delay the others. Notifications are dropped instead of blocking the reconcile loops if
+// the queue of a sink is full.
+type Notifier struct {
+	events        map[NotificationEvent]bool
+	batchInterval time.Duration
+	queues        []*notificationQueue
+}
+
+type notificationQueue struct {
+	sink          NotificationSink
+	limiter       *rate.Limiter
+	notifications chan Notification
+}
+
+// NewNotifier creates a notifier sending the enabled events, all events are enabled if events is empty
+func NewNotifier(events []NotificationEvent, batchInterval time.Duration) *Notifier {
+	if len(events) == 0 {
+		events = notificationEvents
+	}
+
+	enabled := map[NotificationEvent]bool{}
+	for _, event := range events {
+		enabled[event] = true
+	}
+
+	return &Notifier{
+		events:        enabled,
+		batchInterval: batchInterval,
+	}
+}
+
+// parseNotificationEvents parses a comma separated list of events
+func parseNotificationEvents(value string) ([]NotificationEvent, error) {
+	var events []NotificationEvent
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		event := NotificationEvent(name)
+		known := false
+		for _, e := range notificationEvents {
+			if e == event {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown notification event %s", name)
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// AddSink adds a sink which is sent at most limit requests per second with the burst, must be called before Run
+func (n *Notifier) AddSink(sink NotificationSink, limit rate.Limit, burst int) {
+	n.queues = append(n.queues, &notificationQueue{
+		sink:          sink,
+		limiter:       rate.NewLimiter(limit, burst),
+		notifications: make(chan Notification, notificationQueueSize),
+	})
+}
+
+// Notify queues the notification for all sinks if its event is enabled
+func (n *Notifier) Notify(notification Notification) {
+	if !n.events[notification.Event] {
+		return
+	}
+
+	if notification.Time.IsZero() {
+		notification.Time = time.Now()
+	}
+	if notification.Severity == "" {
+		notification.Severity = NotificationSeverityInfo
+	}
+
+	for _, queue := range n.queues {
+		select {
+		case queue.notifications <- notification:
+		default:
+			Logger.Warningf("%s notification queue is full, dropping %s notification", queue.sink.Name(), notification.Event)
+		}
+	}
+}
+
+// Run sends the queued notifications until the context is cancelled, pending notifications are flushed on shutdown
+func (n *Notifier) Run(ctx context.Context) {
+	done := make(chan struct{})
+	for _, queue := range n.queues {
+		go func(queue *notificationQueue) {
+			queue.run(ctx, n.batchInterval)
+			done <- struct{}{}
+		}(queue)
+	}
+
+	for range n.queues {
+		<-done
+	}
+}
+
+func (q *notificationQueue) run(ctx context.Context, batchInterval time.Duration) {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	var batch []Notification
+	for {
+		select {
+		case notification := <-q.notifications:
+			batch = append(batch, notification)
+			if len(batch) >= q.sink.MaxBatchSize() {
+				q.send(ctx, batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				q.send(ctx, batch)
+				batch = nil
+			}
+		case <-ctx.Done():
+			q.flush(batch)
+			return
+		}
+	}
+}
+
+// flush sends the batch and the queued notifications once the operator context has been cancelled
+func (q *notificationQueue) flush(batch []Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationFlushTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case notification := <-q.notifications:
+			batch = append(batch, notification)
+			if len(batch) >= q.sink.MaxBatchSize() {
+				q.send(ctx, batch)
+				batch = nil
+			}
+		default:
+			if len(batch) > 0 {
+				q.send(ctx, batch)
+			}
+			return
+		}
+	}
+}
+
+func (q *notificationQueue) send(ctx context.Context, batch []Notification) {
+	err := q.limiter.Wait(ctx)
+	if err != nil {
+		Logger.Warningf("dropping %d %s notifications: %v", len(batch), q.sink.Name(), err)
+		return
+	}
+
+	err = q.sink.Send(ctx, batch)
+	if err != nil {
+		Logger.Warningf("failed to send %d %s notifications: %v", len(batch), q.sink.Name(), err)
+	}
+}
+
+// gameServerNotificationFields describes the game server in notifications, empty settings are left out
+func gameServerNotificationFields(gameServer *veverseV1.GameServer) []NotificationField {
+	var fields []NotificationField
+	for _, field := range []NotificationField{
+		{Name: "World", Value: gameServer.Spec.Settings.World.Id},
+		{Name: "Release", Value: gameServer.Spec.Settings.Release.Id},
+		{Name: "Image", Value: gameServer.Spec.Settings.Server.Image},
+	} {
+		if field.Value != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// notify sends the notification with the notifier of the context, notifications are disabled if there is no notifier
+func notify(ctx context.Context, notification Notification) {
+	notifier, ok := ctx.Value("notifier").(*Notifier)
+	if !ok {
+		return
+	}
+
+	notifier.Notify(notification)
+}
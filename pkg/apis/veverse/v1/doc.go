@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=veverse.com
+
+// Package v1 contains the veverse.com/v1 API types managed by the operator.
+package v1
@@ -0,0 +1,491 @@
+package v1
+
+import (
+	apiV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GameServer describes a single game server instance, the operator creates a deployment and a service for each of them
+type GameServer struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GameServerSpec   `json:"spec"`
+	Status GameServerStatus `json:"status,omitempty"`
+}
+
+// GameServerSpec is the specification of the game server
+type GameServerSpec struct {
+	// UUID of the game server
+	Id string `json:"id"`
+	// Settings for the game server
+	Settings Settings `json:"settings"`
+	// Environment variables that will be passed to the server
+	Env []EnvVar `json:"env,omitempty"`
+	// Policy of applying spec changes which restart the game server, Immediate if empty
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+}
+
+// UpdatePolicy defines when spec changes which restart the game server are applied to the running game server
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyImmediate applies spec changes as soon as they are observed
+	UpdatePolicyImmediate UpdatePolicy = "Immediate"
+	// UpdatePolicyWhenEmpty defers spec changes until no players are connected to the game server
+	UpdatePolicyWhenEmpty UpdatePolicy = "WhenEmpty"
+)
+
+// Settings for the game server
+type Settings struct {
+	Api     ApiSettings     `json:"api"`
+	App     AppSettings     `json:"app"`
+	Release ReleaseSettings `json:"release"`
+	Players PlayerSettings  `json:"players"`
+	World   WorldSettings   `json:"world"`
+	Server  ServerSettings  `json:"server"`
+}
+
+// ApiSettings are used by the game server to authenticate with the VeVerse API
+type ApiSettings struct {
+	V1 ApiV1Settings `json:"v1"`
+	V2 ApiV2Settings `json:"v2"`
+	// Name of the secret with the API credentials (apiV1Key, apiV2Email and apiV2Password), the operator default
+	// credentials secret is used if empty
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+type ApiV1Settings struct {
+	Url string `json:"url,omitempty"`
+	// Deprecated: stored in plaintext, moved to a secret by the operator, use CredentialsSecret instead
+	Key string `json:"key,omitempty"`
+}
+
+type ApiV2Settings struct {
+	Url string `json:"url,omitempty"`
+	// Email of the user to authenticate with the VeVerse API, overrides the email of the credentials secret
+	Email string `json:"email,omitempty"`
+	// Deprecated: stored in plaintext, moved to a secret by the operator, use CredentialsSecret instead
+	Password string `json:"password,omitempty"`
+}
+
+// AppSettings is the app metadata for the game server
+type AppSettings struct {
+	// UUID of the app to start the server, if empty, default app (VeVerse) will be used
+	Id string `json:"id,omitempty"`
+}
+
+// ReleaseSettings is the release metadata for the game server
+type ReleaseSettings struct {
+	// UUID of the release to start the server, if empty, the latest release of specified app will be used
+	Id string `json:"id,omitempty"`
+}
+
+// PlayerSettings are the settings for the game server players
+type PlayerSettings struct {
+	// Maximum number of players allowed to connect to the game server
+	Max int64 `json:"max,omitempty"`
+}
+
+// WorldSettings are the game server world settings
+type WorldSettings struct {
+	// UUID of the world to start at the server, if empty, the default world will be used
+	Id string `json:"id,omitempty"`
+}
+
+// ServerSettings are the game server container settings
+type ServerSettings struct {
+	// Image pull secret names
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// Image of the game server to start
+	Image string `json:"image"`
+	// Public DNS of the VeVerse server, port is assigned by the operator with the service
+	Host string `json:"host,omitempty"`
+	// How players reach the game server, the operator default is used if empty
+	Exposure *ExposureSettings `json:"exposure,omitempty"`
+	// Ports of the game server besides the unreal server port, e.g. the beacon, query, voice chat or metrics port
+	Ports []GameServerPort `json:"ports,omitempty"`
+	// Health checks of the game server container, operator defaults are used if empty
+	Probes *ProbeSettings `json:"probes,omitempty"`
+	// Resources and node placement of the game server pod, merged over the operator defaults
+	Scheduling *SchedulingSettings `json:"scheduling,omitempty"`
+	// Draining of the game server before it is torn down, operator defaults are used if empty
+	Drain *DrainSettings `json:"drain,omitempty"`
+}
+
+// ExposureType is the way the game server port is exposed to players
+type ExposureType string
+
+const (
+	// ExposureTypeNodePort exposes the game server with a node port service, players connect to the server host
+	ExposureTypeNodePort ExposureType = "NodePort"
+	// ExposureTypeHostPort binds a host port allocated by the operator on the node running the game server pod
+	ExposureTypeHostPort ExposureType = "HostPort"
+	// ExposureTypeLoadBalancer exposes the game server with a load balancer service of the cloud provider
+	ExposureTypeLoadBalancer ExposureType = "LoadBalancer"
+	// ExposureTypePodIP lets players connect to the pod IP directly, for clusters with routable pod networks
+	ExposureTypePodIP ExposureType = "PodIP"
+)
+
+// ExposureSettings configure how players reach the game server
+type ExposureSettings struct {
+	// Exposure strategy, the operator default is used if empty
+	Type ExposureType `json:"type,omitempty"`
+	// Annotations of the load balancer service, merged over the operator defaults
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PortPolicy selects who can reach a game server port
+type PortPolicy string
+
+const (
+	// PortPolicyPublic exposes the port to players with the exposure of the game server
+	PortPolicyPublic PortPolicy = "Public"
+	// PortPolicyInternal only declares the port on the container, it is reachable within the cluster at the pod IP
+	PortPolicyInternal PortPolicy = "Internal"
+)
+
+// GameServerPort is a named port the game server listens on
+type GameServerPort struct {
+	// Name of the port, unique within the game server, "unreal" is reserved for the unreal server port
+	Name string `json:"name"`
+	// Protocol of the port, UDP if empty
+	Protocol apiV1.Protocol `json:"protocol,omitempty"`
+	// Port the game server listens on in the container
+	ContainerPort int32 `json:"containerPort"`
+	// Who can reach the port, Public if empty
+	Policy PortPolicy `json:"policy,omitempty"`
+}
+
+// DrainSettings configure how connected players are given time to leave before the game server is torn down
+type DrainSettings struct {
+	// Maximum time to wait for all players to leave after the game server resource has been deleted
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// Time the game server process has to exit after it has been sent SIGTERM
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+	// HTTP endpoint of the game server called with GET when draining starts and by the pod preStop hook
+	Http *DrainHttp `json:"http,omitempty"`
+}
+
+// DrainHttp is the HTTP drain endpoint of the game server
+type DrainHttp struct {
+	Path string `json:"path"`
+	Port int32  `json:"port"`
+}
+
+// SchedulingSettings configure resources and node placement of the game server pod, unset fields are inherited from the
+// operator defaults
+type SchedulingSettings struct {
+	// CPU and memory requests and limits of the game server container
+	Resources *apiV1.ResourceRequirements `json:"resources,omitempty"`
+	// Labels of the nodes the game server can run on
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Taints tolerated by the game server pod
+	Tolerations []apiV1.Toleration `json:"tolerations,omitempty"`
+	// Affinity of the game server pod, world spreading is added to it
+	Affinity *apiV1.Affinity `json:"affinity,omitempty"`
+	// Prefer running game servers of the same world on different nodes
+	SpreadByWorld *bool `json:"spreadByWorld,omitempty"`
+	// Run the game server only on nodes dedicated to game servers (labeled and tainted with veverse.com/dedicated=gameserver)
+	DedicatedNodes *bool `json:"dedicatedNodes,omitempty"`
+}
+
+// ProbeSettings configure the health checks of the game server container
+type ProbeSettings struct {
+	// Checks if the game server has started, other probes are disabled until it succeeds
+	Startup *Probe `json:"startup,omitempty"`
+	// Checks if the game server accepts connections
+	Readiness *Probe `json:"readiness,omitempty"`
+	// Checks if the game server is alive, the container is restarted if it fails
+	Liveness *Probe `json:"liveness,omitempty"`
+	// Sidecar container used by probes of type sidecar
+	Sidecar *ProbeSidecar `json:"sidecar,omitempty"`
+}
+
+// ProbeType is the way the game server is checked
+type ProbeType string
+
+const (
+	// ProbeTypeNone disables the probe
+	ProbeTypeNone ProbeType = "none"
+	// ProbeTypeUdp checks that the game server listens on its UDP port
+	ProbeTypeUdp ProbeType = "udp"
+	// ProbeTypeHttp queries the HTTP health endpoint of the game server
+	ProbeTypeHttp ProbeType = "http"
+	// ProbeTypeSidecar queries the HTTP health endpoint of the sidecar container, which queries the game server
+	ProbeTypeSidecar ProbeType = "sidecar"
+)
+
+// Probe is a single health check of the game server container, empty fields are set to operator defaults
+type Probe struct {
+	Type ProbeType `json:"type,omitempty"`
+	// HTTP path of the health endpoint for http and sidecar probes
+	Path string `json:"path,omitempty"`
+	// Port of the health endpoint for http and sidecar probes
+	Port                int32 `json:"port,omitempty"`
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32 `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int32 `json:"timeoutSeconds,omitempty"`
+	FailureThreshold    int32 `json:"failureThreshold,omitempty"`
+}
+
+// ProbeSidecar is a container running next to the game server, which queries the game server and exposes the result
+// as an HTTP health endpoint
+type ProbeSidecar struct {
+	// Image of the sidecar container, operator default is used if empty
+	Image string `json:"image,omitempty"`
+	// Port of the sidecar health endpoint
+	Port int32 `json:"port,omitempty"`
+}
+
+// EnvVar is an environment variable passed to the game server container
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// GameServerPhase is the lifecycle phase of the game server, matches the game server record status
+type GameServerPhase string
+
+const (
+	GameServerPhaseCreated  GameServerPhase = "created"
+	GameServerPhaseStarting GameServerPhase = "starting"
+	GameServerPhaseOnline   GameServerPhase = "online"
+	// GameServerPhaseDraining is set while the deleted game server waits for its players to leave
+	GameServerPhaseDraining GameServerPhase = "draining"
+	GameServerPhaseOffline  GameServerPhase = "offline"
+	GameServerPhaseError    GameServerPhase = "error"
+)
+
+// Game server condition types
+const (
+	// GameServerConditionDeploymentAvailable is true when the game server deployment has an available replica
+	GameServerConditionDeploymentAvailable = "DeploymentAvailable"
+	// GameServerConditionPortAssigned is true when the game server service got a node port assigned
+	GameServerConditionPortAssigned = "PortAssigned"
+	// GameServerConditionReady is true when the game server pod is ready
+	GameServerConditionReady = "Ready"
+	// GameServerConditionReconciled is false when the last reconcile of the game server failed
+	GameServerConditionReconciled = "Reconciled"
+	// GameServerConditionSpecSynced is false when the game server deployment does not match the game server spec yet
+	GameServerConditionSpecSynced = "SpecSynced"
+)
+
+// GameServerStatus is the observed state of the game server, maintained by the operator
+type GameServerStatus struct {
+	// Lifecycle phase of the game server
+	Phase GameServerPhase `json:"phase,omitempty"`
+	// Node port assigned to the game server by its service
+	NodePort int32 `json:"nodePort,omitempty"`
+	// Host port allocated to the unreal server port by the operator, if the game server is exposed with host ports
+	HostPort int32 `json:"hostPort,omitempty"`
+	// Exposure strategy of the game server
+	Exposure ExposureType `json:"exposure,omitempty"`
+	// Public DNS or address of the game server
+	Host string `json:"host,omitempty"`
+	// Port players connect to the unreal server at the host
+	Port int32 `json:"port,omitempty"`
+	// Ports of the game server and where they are reached
+	Ports []GameServerPortStatus `json:"ports,omitempty"`
+	// Name of the node running the game server pod
+	NodeName string `json:"nodeName,omitempty"`
+	// Name of the game server pod
+	PodName string `json:"podName,omitempty"`
+	// Time when the game server pod has been started
+	StartedAt *metaV1.Time `json:"startedAt,omitempty"`
+	// Time when the game server pod became ready
+	ReadyAt *metaV1.Time `json:"readyAt,omitempty"`
+	// Time when the game server started draining
+	DrainStartedAt *metaV1.Time `json:"drainStartedAt,omitempty"`
+	// Time when players have last been allocated to the game server
+	AllocatedAt *metaV1.Time `json:"allocatedAt,omitempty"`
+	// Player slots reserved by allocations since the previous allocations expired
+	AllocatedPlayers int32 `json:"allocatedPlayers,omitempty"`
+	// Error of the last failed reconcile, empty if the last reconcile succeeded
+	LastError string `json:"lastError,omitempty"`
+	// Generation of the game server spec observed by the operator
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the game server
+	Conditions []metaV1.Condition `json:"conditions,omitempty"`
+}
+
+// GameServerPortStatus is a port of the game server and the endpoint it is reached at
+type GameServerPortStatus struct {
+	Name          string         `json:"name"`
+	Protocol      apiV1.Protocol `json:"protocol"`
+	ContainerPort int32          `json:"containerPort"`
+	Policy        PortPolicy     `json:"policy"`
+	// Host port allocated to the port by the operator, if the game server is exposed with host ports
+	HostPort int32 `json:"hostPort,omitempty"`
+	// Node port assigned to the port by the service
+	NodePort int32 `json:"nodePort,omitempty"`
+	// Host the port is reached at, the pod IP for internal ports
+	Host string `json:"host,omitempty"`
+	// Port at the host, zero until it has been assigned
+	Port int32 `json:"port,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GameServerList is a list of game servers
+type GameServerList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+
+	Items []GameServer `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GameServerFleet keeps a number of game servers of the same world and release running, so players can join a warm
+// game server instead of waiting for a new one to boot
+type GameServerFleet struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GameServerFleetSpec   `json:"spec"`
+	Status GameServerFleetStatus `json:"status,omitempty"`
+}
+
+// GameServerFleetSpec is the specification of the fleet
+type GameServerFleetSpec struct {
+	// UUID of the world to start at the game servers
+	WorldId string `json:"worldId"`
+	// UUID of the release to start the game servers, changing it replaces the game servers one by one
+	ReleaseId string `json:"releaseId"`
+	// UUID of the app to start the game servers, if empty, default app (VeVerse) will be used
+	AppId string `json:"appId,omitempty"`
+	// Number of game servers to keep running
+	Replicas int32 `json:"replicas"`
+	// Spec of the game servers, the id, app, release and world are set by the fleet
+	Template GameServerSpec `json:"template"`
+}
+
+// Game server fleet condition types
+const (
+	// GameServerFleetConditionReady is true when all game servers of the fleet run the fleet release and are online
+	GameServerFleetConditionReady = "Ready"
+	// GameServerFleetConditionReconciled is false when the last reconcile of the fleet failed
+	GameServerFleetConditionReconciled = "Reconciled"
+)
+
+// GameServerFleetStatus is the observed state of the fleet, maintained by the operator
+type GameServerFleetStatus struct {
+	// Number of game servers of the fleet
+	Replicas int32 `json:"replicas"`
+	// Number of online game servers of the fleet
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Number of game servers running the fleet release
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// Error of the last failed reconcile, empty if the last reconcile succeeded
+	LastError string `json:"lastError,omitempty"`
+	// Generation of the fleet spec observed by the operator
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the fleet
+	Conditions []metaV1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GameServerFleetList is a list of game server fleets
+type GameServerFleetList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+
+	Items []GameServerFleet `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FleetAutoscaler scales the replicas of a game server fleet to keep a buffer of free player slots
+type FleetAutoscaler struct {
+	metaV1.TypeMeta   `json:",inline"`
+	metaV1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   FleetAutoscalerSpec   `json:"spec"`
+	Status FleetAutoscalerStatus `json:"status,omitempty"`
+}
+
+// FleetAutoscalerSpec is the specification of the fleet autoscaler
+type FleetAutoscalerSpec struct {
+	// Name of the fleet to scale
+	FleetName string `json:"fleetName"`
+	// Free player slots to keep, either a number of slots or a percentage of the fleet capacity (e.g. "20%")
+	BufferSize intstr.IntOrString `json:"bufferSize"`
+	// Minimum number of game servers
+	MinReplicas int32 `json:"minReplicas,omitempty"`
+	// Maximum number of game servers
+	MaxReplicas int32 `json:"maxReplicas"`
+	// Time to wait after scaling before scaling up again, 30 seconds if empty
+	ScaleUpCooldownSeconds int32 `json:"scaleUpCooldownSeconds,omitempty"`
+	// Time to wait after scaling before scaling down again, 5 minutes if empty
+	ScaleDownCooldownSeconds int32 `json:"scaleDownCooldownSeconds,omitempty"`
+	// Time windows overriding the buffer and bounds, the first active window is used
+	Schedules []FleetAutoscalerSchedule `json:"schedules,omitempty"`
+}
+
+// FleetAutoscalerSchedule overrides the buffer and bounds of the autoscaler during a daily time window
+type FleetAutoscalerSchedule struct {
+	// Name of the window, reported in the status and the decision log
+	Name string `json:"name"`
+	// Days of the week the window is active on (Mon, Tue, ...), every day if empty
+	Days []string `json:"days,omitempty"`
+	// Start of the window (HH:MM), inclusive
+	Start string `json:"start"`
+	// End of the window (HH:MM), exclusive, windows ending before they start span midnight
+	End string `json:"end"`
+	// IANA time zone of the window, UTC if empty
+	TimeZone string `json:"timeZone,omitempty"`
+	// Free player slots to keep during the window
+	BufferSize *intstr.IntOrString `json:"bufferSize,omitempty"`
+	// Minimum number of game servers during the window
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Maximum number of game servers during the window
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+}
+
+// Fleet autoscaler condition types
+const (
+	// FleetAutoscalerConditionAbleToScale is false when the autoscaler can not read the fleet occupancy or scale the fleet
+	FleetAutoscalerConditionAbleToScale = "AbleToScale"
+	// FleetAutoscalerConditionScalingLimited is true when the desired replicas are capped by the bounds
+	FleetAutoscalerConditionScalingLimited = "ScalingLimited"
+)
+
+// FleetAutoscalerStatus is the observed state of the fleet autoscaler, maintained by the operator
+type FleetAutoscalerStatus struct {
+	// Replicas of the fleet when the autoscaler last checked it
+	CurrentReplicas int32 `json:"currentReplicas"`
+	// Replicas the autoscaler wants the fleet to have
+	DesiredReplicas int32 `json:"desiredReplicas"`
+	// Players connected to the game servers of the fleet
+	Players int64 `json:"players"`
+	// Player slots of the game servers of the fleet
+	Capacity int64 `json:"capacity"`
+	// Name of the active schedule window, empty if none is active
+	ActiveSchedule string `json:"activeSchedule,omitempty"`
+	// Time when the autoscaler last scaled the fleet
+	LastScaleTime *metaV1.Time `json:"lastScaleTime,omitempty"`
+	// Reason of the last decision of the autoscaler
+	LastDecision string `json:"lastDecision,omitempty"`
+	// Generation of the autoscaler spec observed by the operator
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions of the autoscaler
+	Conditions []metaV1.Condition `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// FleetAutoscalerList is a list of fleet autoscalers
+type FleetAutoscalerList struct {
+	metaV1.TypeMeta `json:",inline"`
+	metaV1.ListMeta `json:"metadata,omitempty"`
+
+	Items []FleetAutoscaler `json:"items"`
+}
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GameServerFromUnstructured converts an unstructured game server object (e.g. received from the dynamic client) to the typed game server
+func GameServerFromUnstructured(u *unstructured.Unstructured) (*GameServer, error) {
+	if u == nil {
+		return nil, fmt.Errorf("unstructured object is nil")
+	}
+
+	if gvk := u.GroupVersionKind(); !gvk.Empty() && gvk != SchemeGroupVersion.WithKind("GameServer") {
+		return nil, fmt.Errorf("unexpected object kind: %s", gvk.String())
+	}
+
+	gameServer := &GameServer{}
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), gameServer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert unstructured object to game server: %v", err)
+	}
+
+	return gameServer, nil
+}
+
+// ToUnstructured converts the game server to an unstructured object
+func (in *GameServer) ToUnstructured() (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert game server to unstructured object: %v", err)
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(SchemeGroupVersion.WithKind("GameServer"))
+
+	return u, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreListers "k8s.io/client-go/listers/core/v1"
+)
+
+// getGameServerPodClusterResource returns the most recently created pod of the game server deployment from the pod
+// informer cache, nil if there is no pod
+func getGameServerPodClusterResource(ctx context.Context, id uuid.UUID) (*apiV1.Pod, error) {
+	podLister := ctx.Value("podLister").(coreListers.PodLister)
+	namespace := ctx.Value("namespace").(string)
+
+	resourceName := getResourceName(id)
+
+	pods, err := podLister.Pods(namespace).List(labels.SelectorFromSet(labels.Set{"app": resourceName}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+
+	var pod *apiV1.Pod
+	for _, p := range pods {
+		if p.DeletionTimestamp != nil {
+			continue
+		}
+		if pod == nil || p.CreationTimestamp.After(pod.CreationTimestamp.Time) {
+			pod = p
+		}
+	}
+
+	return pod, nil
+}
+
+// getPodReadyCondition returns the ready condition of the pod, nil if the pod has not reported it yet
+func getPodReadyCondition(pod *apiV1.Pod) *apiV1.PodCondition {
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == apiV1.PodReady {
+			return &pod.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
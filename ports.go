@@ -0,0 +1,108 @@
+package main
+
+import (
+	apiV1 "k8s.io/api/core/v1"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+// getGameServerPorts returns the ports of the game server, the unreal server port followed by the ports of the spec
+// with the default protocol and policy applied
+func getGameServerPorts(gameServer *veverseV1.GameServer) []veverseV1.GameServerPort {
+	ports := []veverseV1.GameServerPort{
+		{
+			Name:          gameServerPortName,
+			Protocol:      apiV1.ProtocolUDP,
+			ContainerPort: gameServerPort,
+			Policy:        veverseV1.PortPolicyPublic,
+		},
+	}
+
+	for _, port := range gameServer.Spec.Settings.Server.Ports {
+		if port.Protocol == "" {
+			port.Protocol = apiV1.ProtocolUDP
+		}
+		if port.Policy == "" {
+			port.Policy = veverseV1.PortPolicyPublic
+		}
+		ports = append(ports, port)
+	}
+
+	return ports
+}
+
+// getPublicGameServerPorts returns the ports of the game server exposed to players
+func getPublicGameServerPorts(gameServer *veverseV1.GameServer) []veverseV1.GameServerPort {
+	var ports []veverseV1.GameServerPort
+	for _, port := range getGameServerPorts(gameServer) {
+		if port.Policy == veverseV1.PortPolicyPublic {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports
+}
+
+// buildGameServerContainerPorts declares the ports of the game server on its container
+func buildGameServerContainerPorts(ports []veverseV1.GameServerPort) []apiV1.ContainerPort {
+	containerPorts := make([]apiV1.ContainerPort, len(ports))
+	for i, port := range ports {
+		containerPorts[i] = apiV1.ContainerPort{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			Protocol:      port.Protocol,
+		}
+	}
+
+	return containerPorts
+}
+
+// getGameServerHostPort returns the host port allocated to the named port from the status, zero if it has none
+func getGameServerHostPort(status *veverseV1.GameServerStatus, name string) int32 {
+	for _, port := range status.Ports {
+		if port.Name == name && port.HostPort > 0 {
+			return port.HostPort
+		}
+	}
+
+	// game servers exposed before the ports were added to the status only have the unreal server port
+	if name == gameServerPortName {
+		return status.HostPort
+	}
+
+	return 0
+}
+
+// buildGameServerPortStatuses describes where the ports of the game server are reached, public ports at the host and
+// ports of the exposure, internal ports at the pod IP
+func buildGameServerPortStatuses(gameServer *veverseV1.GameServer, service *apiV1.Service, pod *apiV1.Pod, host string, endpointPorts map[string]int32) []veverseV1.GameServerPortStatus {
+	ports := getGameServerPorts(gameServer)
+
+	statuses := make([]veverseV1.GameServerPortStatus, len(ports))
+	for i, port := range ports {
+		status := veverseV1.GameServerPortStatus{
+			Name:          port.Name,
+			Protocol:      port.Protocol,
+			ContainerPort: port.ContainerPort,
+			Policy:        port.Policy,
+			HostPort:      getGameServerHostPort(&gameServer.Status, port.Name),
+		}
+
+		if service != nil {
+			status.NodePort = getServiceNodePort(service, port.Name)
+		}
+
+		if port.Policy == veverseV1.PortPolicyPublic {
+			status.Port = endpointPorts[port.Name]
+			if status.Port > 0 {
+				status.Host = host
+			}
+		} else if pod != nil && pod.Status.PodIP != "" {
+			status.Host = pod.Status.PodIP
+			status.Port = port.ContainerPort
+		}
+
+		statuses[i] = status
+	}
+
+	return statuses
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// EnvServerPort is passed to the probe sidecar, so it knows which port to query
+	EnvServerPort = "VE_SERVER_PORT"
+
+	defaultProbeHttpPath    = "/healthz"
+	defaultProbeHttpPort    = 8080
+	defaultProbeSidecarPort = 8081
+)
+
+// probeSidecarImage is the default image of the probe sidecar container, set from the env
+var probeSidecarImage = ""
+
+// default probes, the startup probe allows the game server to load for up to 10 minutes
+var (
+	defaultStartupProbe   = veverseV1.Probe{Type: veverseV1.ProbeTypeUdp, PeriodSeconds: 5, TimeoutSeconds: 1, FailureThreshold: 120}
+	defaultReadinessProbe = veverseV1.Probe{Type: veverseV1.ProbeTypeUdp, PeriodSeconds: 5, TimeoutSeconds: 1, FailureThreshold: 3}
+	defaultLivenessProbe  = veverseV1.Probe{Type: veverseV1.ProbeTypeUdp, PeriodSeconds: 15, TimeoutSeconds: 5, FailureThreshold: 4}
+)
+
+// buildGameServerProbes builds the startup, readiness and liveness probes of the game server container and the probe
+// sidecar container if any of the probes requires it
+func buildGameServerProbes(settings *veverseV1.ProbeSettings) (startup *apiV1.Probe, readiness *apiV1.Probe, liveness *apiV1.Probe, sidecar *apiV1.Container, err error) {
+	if settings == nil {
+		settings = &veverseV1.ProbeSettings{}
+	}
+
+	sidecarPort := int32(defaultProbeSidecarPort)
+	if settings.Sidecar != nil && settings.Sidecar.Port > 0 {
+		sidecarPort = settings.Sidecar.Port
+	}
+
+	startupProbe := mergeProbe(settings.Startup, defaultStartupProbe)
+	readinessProbe := mergeProbe(settings.Readiness, defaultReadinessProbe)
+	livenessProbe := mergeProbe(settings.Liveness, defaultLivenessProbe)
+
+	if startup, err = buildProbe(startupProbe, sidecarPort); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid startup probe: %v", err)
+	}
+	if readiness, err = buildProbe(readinessProbe, sidecarPort); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid readiness probe: %v", err)
+	}
+	if liveness, err = buildProbe(livenessProbe, sidecarPort); err != nil {
+		return nil, nil, nil, nil, fmt.Errorf("invalid liveness probe: %v", err)
+	}
+
+	if startupProbe.Type == veverseV1.ProbeTypeSidecar || readinessProbe.Type == veverseV1.ProbeTypeSidecar || livenessProbe.Type == veverseV1.ProbeTypeSidecar {
+		image := probeSidecarImage
+		if settings.Sidecar != nil && settings.Sidecar.Image != "" {
+			image = settings.Sidecar.Image
+		}
+		if image == "" {
+			return nil, nil, nil, nil, fmt.Errorf("sidecar probe requires a sidecar image")
+		}
+
+		sidecar = &apiV1.Container{
+			Name:  "probe",
+			Image: image,
+			Env: []apiV1.EnvVar{
+				{Name: EnvServerPort, Value: fmt.Sprintf("%d", gameServerPort)},
+			},
+			Ports: []apiV1.ContainerPort{
+				{
+					Name:          "probe",
+					ContainerPort: sidecarPort,
+					Protocol:      apiV1.ProtocolTCP,
+				},
+			},
+		}
+	}
+
+	return startup, readiness, liveness, sidecar, nil
+}
+
+// mergeProbe fills empty fields of the probe with the defaults
+func mergeProbe(probe *veverseV1.Probe, defaults veverseV1.Probe) veverseV1.Probe {
+	if probe == nil {
+		return defaults
+	}
+
+	merged := *probe
+	if merged.Type == "" {
+		merged.Type = defaults.Type
+	}
+	if merged.InitialDelaySeconds == 0 {
+		merged.InitialDelaySeconds = defaults.InitialDelaySeconds
+	}
+	if merged.PeriodSeconds == 0 {
+		merged.PeriodSeconds = defaults.PeriodSeconds
+	}
+	if merged.TimeoutSeconds == 0 {
+		merged.TimeoutSeconds = defaults.TimeoutSeconds
+	}
+	if merged.FailureThreshold == 0 {
+		merged.FailureThreshold = defaults.FailureThreshold
+	}
+
+	return merged
+}
+
+// buildProbe converts the game server probe to the container probe, nil if the probe is disabled
+func buildProbe(probe veverseV1.Probe, sidecarPort int32) (*apiV1.Probe, error) {
+	var handler apiV1.ProbeHandler
+
+	switch probe.Type {
+	case veverseV1.ProbeTypeNone:
+		return nil, nil
+	case veverseV1.ProbeTypeUdp:
+		// check that the game server has bound its UDP port, /proc/net/udp lists local addresses as hex ip:port
+		handler.Exec = &apiV1.ExecAction{
+			Command: []string{"sh", "-c", fmt.Sprintf("grep -qi ':%04X ' /proc/net/udp /proc/net/udp6", gameServerPort)},
+		}
+	case veverseV1.ProbeTypeHttp, veverseV1.ProbeTypeSidecar:
+		path := probe.Path
+		if path == "" {
+			path = defaultProbeHttpPath
+		}
+
+		port := probe.Port
+		if port == 0 && probe.Type == veverseV1.ProbeTypeSidecar {
+			port = sidecarPort
+		} else if port == 0 {
+			port = defaultProbeHttpPort
+		}
+
+		handler.HTTPGet = &apiV1.HTTPGetAction{
+			Path: path,
+			Port: intstr.FromInt(int(port)),
+		}
+	default:
+		return nil, fmt.Errorf("unknown probe type %s", probe.Type)
+	}
+
+	return &apiV1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		FailureThreshold:    probe.FailureThreshold,
+	}, nil
+}
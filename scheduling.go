@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	apiV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"sigs.k8s.io/yaml"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// LabelWorldId is the id of the world running at the game server, used to spread game servers of the same world
+	LabelWorldId = "veverse.com/world-id"
+	// LabelDedicated is the label and taint key of nodes dedicated to game servers
+	LabelDedicated = "veverse.com/dedicated"
+	// dedicatedGameServer is the value of the dedicated node label and taint
+	dedicatedGameServer = "gameserver"
+)
+
+// SchedulingDefaults are operator wide scheduling settings, release settings override app settings, which override
+// the defaults. Game server spec settings override all of them.
+type SchedulingDefaults struct {
+	Defaults *veverseV1.SchedulingSettings            `json:"defaults,omitempty"`
+	Apps     map[string]*veverseV1.SchedulingSettings `json:"apps,omitempty"`
+	Releases map[string]*veverseV1.SchedulingSettings `json:"releases,omitempty"`
+}
+
+// schedulingDefaults are loaded from the file set in the env
+var schedulingDefaults = &SchedulingDefaults{}
+
+// loadSchedulingDefaults reads the scheduling defaults from a YAML or JSON file
+func loadSchedulingDefaults(path string) (*SchedulingDefaults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduling defaults: %v", err)
+	}
+
+	defaults := &SchedulingDefaults{}
+	err = yaml.UnmarshalStrict(data, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scheduling defaults: %v", err)
+	}
+
+	return defaults, nil
+}
+
+// getGameServerScheduling merges the scheduling settings of the game server over the operator defaults for its app and release
+func getGameServerScheduling(settings *veverseV1.Settings) veverseV1.SchedulingSettings {
+	var scheduling veverseV1.SchedulingSettings
+
+	mergeScheduling(&scheduling, schedulingDefaults.Defaults)
+	mergeScheduling(&scheduling, schedulingDefaults.Apps[settings.App.Id])
+	mergeScheduling(&scheduling, schedulingDefaults.Releases[settings.Release.Id])
+	mergeScheduling(&scheduling, settings.Server.Scheduling)
+
+	return scheduling
+}
+
+// mergeScheduling overrides the fields of the scheduling settings which are set in the override
+func mergeScheduling(scheduling *veverseV1.SchedulingSettings, override *veverseV1.SchedulingSettings) {
+	if override == nil {
+		return
+	}
+
+	if override.Resources != nil {
+		scheduling.Resources = override.Resources.DeepCopy()
+	}
+	if override.NodeSelector != nil {
+		scheduling.NodeSelector = make(map[string]string, len(override.NodeSelector))
+		for key, value := range override.NodeSelector {
+			scheduling.NodeSelector[key] = value
+		}
+	}
+	if override.Tolerations != nil {
+		scheduling.Tolerations = make([]apiV1.Toleration, len(override.Tolerations))
+		for i := range override.Tolerations {
+			override.Tolerations[i].DeepCopyInto(&scheduling.Tolerations[i])
+		}
+	}
+	if override.Affinity != nil {
+		scheduling.Affinity = override.Affinity.DeepCopy()
+	}
+	if override.SpreadByWorld != nil {
+		spreadByWorld := *override.SpreadByWorld
+		scheduling.SpreadByWorld = &spreadByWorld
+	}
+	if override.DedicatedNodes != nil {
+		dedicatedNodes := *override.DedicatedNodes
+		scheduling.DedicatedNodes = &dedicatedNodes
+	}
+}
+
+// applyScheduling sets the resources and node placement of the game server pod
+func applyScheduling(podSpec *apiV1.PodSpec, container *apiV1.Container, scheduling veverseV1.SchedulingSettings, worldId string) {
+	if scheduling.Resources != nil {
+		container.Resources = *scheduling.Resources
+	}
+
+	podSpec.NodeSelector = scheduling.NodeSelector
+	podSpec.Tolerations = scheduling.Tolerations
+	podSpec.Affinity = scheduling.Affinity
+
+	if scheduling.DedicatedNodes != nil && *scheduling.DedicatedNodes {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = map[string]string{}
+		}
+		podSpec.NodeSelector[LabelDedicated] = dedicatedGameServer
+
+		podSpec.Tolerations = append(podSpec.Tolerations, apiV1.Toleration{
+			Key:      LabelDedicated,
+			Operator: apiV1.TolerationOpEqual,
+			Value:    dedicatedGameServer,
+			Effect:   apiV1.TaintEffectNoSchedule,
+		})
+	}
+
+	if scheduling.SpreadByWorld != nil && *scheduling.SpreadByWorld && worldId != "" {
+		if podSpec.Affinity == nil {
+			podSpec.Affinity = &apiV1.Affinity{}
+		}
+		if podSpec.Affinity.PodAntiAffinity == nil {
+			podSpec.Affinity.PodAntiAffinity = &apiV1.PodAntiAffinity{}
+		}
+
+		// preferred, so a world can still run more game servers than there are nodes
+		podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, apiV1.WeightedPodAffinityTerm{
+			Weight: 100,
+			PodAffinityTerm: apiV1.PodAffinityTerm{
+				LabelSelector: &metaV1.LabelSelector{
+					MatchLabels: map[string]string{LabelWorldId: worldId},
+				},
+				TopologyKey: "kubernetes.io/hostname",
+			},
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpShutdownTimeout is the time in-flight requests have to finish when the operator stops
+const httpShutdownTimeout = 5 * time.Second
+
+// runHttpServer serves the handler on the address until the context is cancelled
+func runHttpServer(ctx context.Context, address string, handler http.Handler) {
+	serve(ctx, newHttpServer(ctx, address, handler), func(server *http.Server) error {
+		return server.ListenAndServe()
+	})
+}
+
+// runHttpsServer serves the handler with TLS on the address until the context is cancelled, the certificate is requested
+// for each connection, so renewed certificates are used without a restart
+func runHttpsServer(ctx context.Context, address string, handler http.Handler, certificate webhookCertificate) {
+	server := newHttpServer(ctx, address, handler)
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificate.GetCertificate,
+	}
+
+	serve(ctx, server, func(server *http.Server) error {
+		return server.ListenAndServeTLS("", "")
+	})
+}
+
+func newHttpServer(ctx context.Context, address string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// requests get the operator context with the database connection and the clientsets
+		BaseContext: func(_ net.Listener) context.Context { return ctx },
+	}
+}
+
+// serve runs the server until the context is cancelled, in-flight requests have httpShutdownTimeout to finish
+func serve(ctx context.Context, server *http.Server, listen func(server *http.Server) error) {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			Logger.Errorf("failed to shutdown http server: %v", err)
+		}
+	}()
+
+	Logger.Infof("listening on %s", server.Addr)
+
+	err := listen(server)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		Logger.Errorf("failed to serve http on %s: %v", server.Addr, err)
+	}
+}
+
+// writeJson writes the value as the JSON response body with the status code
+func writeJson(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	err := json.NewEncoder(w).Encode(value)
+	if err != nil {
+		Logger.Errorf("failed to write response: %v", err)
+	}
+}
+
+// writeJsonError writes the error message as the JSON response body with the status code
+func writeJsonError(w http.ResponseWriter, statusCode int, message string) {
+	writeJson(w, statusCode, map[string]string{"error": message})
+}
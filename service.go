@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"github.com/gofrs/uuid"
 	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
 )
 
 func getGameServerServiceClusterResource(ctx context.Context, id uuid.UUID) (*apiV1.Service, error) {
@@ -21,157 +22,167 @@ func getGameServerServiceClusterResource(ctx context.Context, id uuid.UUID) (*ap
 
 	service, err := serviceClient.Get(ctx, resourceName, metaV1.GetOptions{})
 	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
 		return nil, fmt.Errorf("failed to get service: %v", err)
 	}
 
 	return service, nil
 }
 
-func createGameServerServiceClusterResource(ctx context.Context, metadata unstructured.Unstructured) error {
+// buildGameServerService builds the service of the given type exposing the public ports of the game server
+func buildGameServerService(id uuid.UUID, gameServer *veverseV1.GameServer, serviceType apiV1.ServiceType, annotations map[string]string) *apiV1.Service {
+	resourceName := getResourceName(id)
+
+	var ports []apiV1.ServicePort
+	for _, port := range getPublicGameServerPorts(gameServer) {
+		ports = append(ports, apiV1.ServicePort{
+			Name:     port.Name,
+			Port:     port.ContainerPort,
+			Protocol: port.Protocol,
+		})
+	}
+
+	return &apiV1.Service{
+		ObjectMeta: metaV1.ObjectMeta{
+			Name:            resourceName,
+			Labels:          getGameServerLabels(id),
+			Annotations:     annotations,
+			OwnerReferences: []metaV1.OwnerReference{getGameServerOwnerReference(gameServer)},
+		},
+		Spec: apiV1.ServiceSpec{
+			Selector: map[string]string{
+				"app": resourceName,
+			},
+			Ports: ports,
+			Type:  serviceType,
+		},
+	}
+}
+
+func createGameServerServiceClusterResource(ctx context.Context, service *apiV1.Service) (*apiV1.Service, error) {
 	namespace, ok := ctx.Value("namespace").(string)
 	if !ok {
-		return fmt.Errorf("namespace not found in context")
+		return nil, fmt.Errorf("namespace not found in context")
 	}
 
 	config, ok := ctx.Value("config").(*rest.Config)
 	if !ok {
-		return fmt.Errorf("config not found in context")
+		return nil, fmt.Errorf("config not found in context")
 	}
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to create a clientset: %v", err)
+		return nil, fmt.Errorf("failed to create a clientset: %v", err)
 	}
 
-	//region Specification
-
-	spec, ok := metadata.Object["spec"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("spec not found in game server metadata")
-	}
-
-	//region ID
-	if _, ok = spec["id"].(string); !ok {
-		return fmt.Errorf("id not found in game server metadata")
-	}
-
-	id, err := uuid.FromString(spec["id"].(string))
+	serviceClient := clientset.CoreV1().Services(namespace)
+	s, err := serviceClient.Create(ctx, service, metaV1.CreateOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to parse id: %v", err)
+		return nil, fmt.Errorf("failed to create service: %v", err)
 	}
 
-	resourceName := getResourceName(id)
-	//endregion
+	return s, nil
+}
 
-	//endregion
+// isGameServerServiceExposed checks if the service has the type, ports and annotations of the desired service
+func isGameServerServiceExposed(service *apiV1.Service, desired *apiV1.Service) bool {
+	if service.Spec.Type != desired.Spec.Type || len(service.Spec.Ports) != len(desired.Spec.Ports) {
+		return false
+	}
 
-	serviceClient := clientset.CoreV1().Services(namespace)
-	serviceResource := &apiV1.Service{
-		ObjectMeta: metaV1.ObjectMeta{
-			Name: resourceName,
-			Labels: map[string]string{
-				"app": resourceName,
-			},
-		},
-		Spec: apiV1.ServiceSpec{
-			Selector: map[string]string{
-				"app": resourceName,
-			},
-			Ports: []apiV1.ServicePort{
-				{
-					Name:     "unreal",
-					Port:     7777,
-					Protocol: "UDP",
-				},
-			},
-			Type: "NodePort",
-		},
+	for i, port := range desired.Spec.Ports {
+		existing := service.Spec.Ports[i]
+		if existing.Name != port.Name || existing.Protocol != port.Protocol || existing.Port != port.Port {
+			return false
+		}
 	}
-	service, err := serviceClient.Create(ctx, serviceResource, metaV1.CreateOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to create service: %v", err)
-	}
-
-	if service != nil {
-		// Get service port and update the game server record in the database
-		for _, port := range service.Spec.Ports {
-			if port.Name == "unreal" && port.Protocol == "UDP" {
-				err = SetGameServerPort(ctx, id, port.NodePort)
-				if err != nil {
-					return fmt.Errorf("failed to update game server record: %v", err)
-				}
-				break
-			}
+
+	for key, value := range desired.Annotations {
+		if service.Annotations[key] != value {
+			return false
 		}
 	}
 
-	return nil
+	return true
 }
 
-func createGameServerServiceClusterResourceWithId(ctx context.Context, id uuid.UUID) (int32, error) {
-	namespace, ok := ctx.Value("namespace").(string)
-	if !ok {
-		return 0, fmt.Errorf("namespace not found in context")
+// updateGameServerServiceClusterResource applies the type, ports and annotations of the desired service to the existing
+// service, the node ports assigned to the existing ports are kept
+func updateGameServerServiceClusterResource(ctx context.Context, service *apiV1.Service, desired *apiV1.Service) (*apiV1.Service, error) {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	ports := make([]apiV1.ServicePort, len(desired.Spec.Ports))
+	for i, port := range desired.Spec.Ports {
+		port.NodePort = getServiceNodePort(service, port.Name)
+		ports[i] = port
 	}
 
-	config, ok := ctx.Value("config").(*rest.Config)
-	if !ok {
-		return 0, fmt.Errorf("config not found in context")
+	service = service.DeepCopy()
+	service.Spec.Type = desired.Spec.Type
+	service.Spec.Ports = ports
+	if len(desired.Annotations) > 0 && service.Annotations == nil {
+		service.Annotations = map[string]string{}
+	}
+	for key, value := range desired.Annotations {
+		service.Annotations[key] = value
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	service, err := clientset.CoreV1().Services(namespace).Update(ctx, service, metaV1.UpdateOptions{})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create a clientset: %v", err)
+		return nil, fmt.Errorf("failed to update service: %v", err)
 	}
 
-	resourceName := getResourceName(id)
+	return service, nil
+}
 
-	serviceClient := clientset.CoreV1().Services(namespace)
-	serviceResource := &apiV1.Service{
-		ObjectMeta: metaV1.ObjectMeta{
-			Name: resourceName,
-			Labels: map[string]string{
-				"app": resourceName,
-			},
-		},
-		Spec: apiV1.ServiceSpec{
-			Selector: map[string]string{
-				"app": resourceName,
-			},
-			Ports: []apiV1.ServicePort{
-				{
-					Name:     "unreal",
-					Port:     7777,
-					Protocol: "UDP",
-				},
-			},
-			Type: "NodePort",
-		},
+// getServiceNodePort returns the node port assigned to the named port of the service, zero if not assigned yet
+func getServiceNodePort(service *apiV1.Service, name string) int32 {
+	for _, port := range service.Spec.Ports {
+		if port.Name == name {
+			return port.NodePort
+		}
 	}
-	s, err := serviceClient.Create(ctx, serviceResource, metaV1.CreateOptions{})
+
+	return 0
+}
+
+// adoptGameServerServiceClusterResource sets the game server as the controller owner of an existing service and adds the game server labels
+func adoptGameServerServiceClusterResource(ctx context.Context, service *apiV1.Service, gameServer *veverseV1.GameServer) (*apiV1.Service, error) {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	id, err := getGameServerId(gameServer)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create service: %v", err)
+		return nil, err
 	}
 
-	for _, port := range s.Spec.Ports {
-		if port.Name == "unreal" && port.Protocol == "UDP" {
-			return port.NodePort, nil
-		}
+	service = service.DeepCopy()
+	if metaV1.GetControllerOf(service) == nil {
+		service.OwnerReferences = append(service.OwnerReferences, getGameServerOwnerReference(gameServer))
 	}
+	setGameServerLabels(&service.ObjectMeta, id)
 
-	return 0, nil
+	service, err = clientset.CoreV1().Services(namespace).Update(ctx, service, metaV1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to adopt service: %v", err)
+	}
+
+	return service, nil
 }
 
 func deleteGameServerServiceClusterResource(ctx context.Context, id uuid.UUID) error {
 	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
 	namespace := ctx.Value("namespace").(string)
 
-	resourceName := id.String()
+	resourceName := getResourceName(id)
 
 	serviceClient := clientset.CoreV1().Services(namespace)
 
 	err := serviceClient.Delete(ctx, resourceName, metaV1.DeleteOptions{})
-	if err != nil {
+	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete service: %v", err)
 	}
 
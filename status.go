@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	vModel "dev.hackerman.me/artheon/veverse-shared/model"
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+	"veverse-server-operator/pkg/client/clientset/versioned"
+)
+
+// updateGameServerStatus observes the game server deployment, service, pod and record and writes the result to the
+// game server status subresource if it has changed
+func updateGameServerStatus(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer, gameServerRecord *vModel.GameServerV2, reconcileErr error) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	status := gameServer.Status.DeepCopy()
+	status.ObservedGeneration = gameServer.Generation
+	status.Host = gameServer.Spec.Settings.Server.Host
+
+	//region Deployment
+	deployment, err := getGameServerDeploymentClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if deployment != nil && deployment.Status.AvailableReplicas > 0 {
+		setGameServerCondition(status, veverseV1.GameServerConditionDeploymentAvailable, metaV1.ConditionTrue, "Available", "deployment has an available replica")
+	} else if deployment != nil {
+		setGameServerCondition(status, veverseV1.GameServerConditionDeploymentAvailable, metaV1.ConditionFalse, "Unavailable", "deployment has no available replicas")
+	} else {
+		setGameServerCondition(status, veverseV1.GameServerConditionDeploymentAvailable, metaV1.ConditionFalse, "NotFound", "deployment does not exist")
+	}
+	//endregion
+
+	//region Spec
+	if desired, err := buildGameServerDeployment(gameServer); err != nil {
+		setGameServerCondition(status, veverseV1.GameServerConditionSpecSynced, metaV1.ConditionFalse, "SpecInvalid", err.Error())
+	} else if deployment != nil && getDeploymentTemplateHash(deployment) == desired.Annotations[AnnotationTemplateHash] {
+		setGameServerCondition(status, veverseV1.GameServerConditionSpecSynced, metaV1.ConditionTrue, "Synced", "deployment matches the game server spec")
+	} else if deployment != nil && (gameServer.Spec.UpdatePolicy == veverseV1.UpdatePolicyWhenEmpty || !isDeploymentSpecChanged(deployment, desired)) {
+		setGameServerCondition(status, veverseV1.GameServerConditionSpecSynced, metaV1.ConditionFalse, "UpdateDeferred", "deployment update is deferred until no players are connected")
+	} else {
+		setGameServerCondition(status, veverseV1.GameServerConditionSpecSynced, metaV1.ConditionFalse, "OutOfSync", "deployment does not match the game server spec")
+	}
+	//endregion
+
+	//region Exposure
+	service, err := getGameServerServiceClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	status.NodePort = 0
+	if service != nil {
+		status.NodePort = getServiceNodePort(service, gameServerPortName)
+	}
+
+	status.Exposure = getGameServerExposureType(gameServer)
+	status.Port = 0
+	var (
+		endpointHost  string
+		endpointPorts map[string]int32
+	)
+	if exposure, err := getGameServerExposure(gameServer); err != nil {
+		setGameServerCondition(status, veverseV1.GameServerConditionPortAssigned, metaV1.ConditionFalse, "ExposureInvalid", err.Error())
+	} else {
+		endpointHost, endpointPorts, err = exposure.getEndpoint(ctx, id, gameServer, service)
+		if err != nil {
+			return err
+		}
+
+		if endpointHost != "" {
+			status.Host = endpointHost
+		}
+		port := endpointPorts[gameServerPortName]
+		status.Port = port
+
+		if port > 0 {
+			setGameServerCondition(status, veverseV1.GameServerConditionPortAssigned, metaV1.ConditionTrue, fmt.Sprintf("%sAssigned", status.Exposure), fmt.Sprintf("%s %d assigned", exposure.describePort(), port))
+		} else {
+			setGameServerCondition(status, veverseV1.GameServerConditionPortAssigned, metaV1.ConditionFalse, fmt.Sprintf("%sPending", status.Exposure), fmt.Sprintf("%s has not been assigned yet", exposure.describePort()))
+		}
+	}
+	//endregion
+
+	//region Pod
+	pod, err := getGameServerPodClusterResource(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	status.PodName = ""
+	status.NodeName = ""
+	status.StartedAt = nil
+	status.ReadyAt = nil
+	if pod != nil {
+		status.PodName = pod.Name
+		status.NodeName = pod.Spec.NodeName
+		status.StartedAt = pod.Status.StartTime
+
+		if ready := getPodReadyCondition(pod); ready != nil && ready.Status == apiV1.ConditionTrue {
+			readyAt := ready.LastTransitionTime
+			status.ReadyAt = &readyAt
+		}
+	}
+
+	if status.ReadyAt != nil {
+		setGameServerCondition(status, veverseV1.GameServerConditionReady, metaV1.ConditionTrue, "PodReady", fmt.Sprintf("pod %s is ready", status.PodName))
+	} else if pod != nil {
+		setGameServerCondition(status, veverseV1.GameServerConditionReady, metaV1.ConditionFalse, "PodNotReady", fmt.Sprintf("pod %s is not ready", status.PodName))
+	} else {
+		setGameServerCondition(status, veverseV1.GameServerConditionReady, metaV1.ConditionFalse, "PodNotFound", "game server has no pod")
+	}
+	//endregion
+
+	//region Ports
+	status.Ports = buildGameServerPortStatuses(gameServer, service, pod, endpointHost, endpointPorts)
+	//endregion
+
+	//region Phase
+	// the game server record is the source of truth for the phase, if it is missing or not updated yet the phase is derived from the pod
+	if gameServerRecord != nil && gameServerRecord.Status != "" && !(gameServerRecord.Status == GameServerStatusCreated && pod != nil) {
+		status.Phase = veverseV1.GameServerPhase(gameServerRecord.Status)
+	} else if pod != nil {
+		status.Phase = veverseV1.GameServerPhaseStarting
+	} else {
+		status.Phase = veverseV1.GameServerPhaseCreated
+	}
+	//endregion
+
+	//region Reconcile
+	if reconcileErr != nil {
+		status.LastError = reconcileErr.Error()
+		setGameServerCondition(status, veverseV1.GameServerConditionReconciled, metaV1.ConditionFalse, "ReconcileError", reconcileErr.Error())
+	} else {
+		status.LastError = ""
+		setGameServerCondition(status, veverseV1.GameServerConditionReconciled, metaV1.ConditionTrue, "Reconciled", "game server resources are up to date")
+	}
+	//endregion
+
+	if equality.Semantic.DeepEqual(status, &gameServer.Status) {
+		return nil
+	}
+
+	becameOnline := status.Phase == veverseV1.GameServerPhaseOnline && gameServer.Status.Phase != veverseV1.GameServerPhaseOnline
+
+	gameServer = gameServer.DeepCopy()
+	gameServer.Status = *status
+
+	_, err = veverseClientset.VeverseV1().GameServers(namespace).UpdateStatus(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update game server status: %v", err)
+	}
+
+	// observed once the phase has been written, so failed updates retried by the next reconcile are not counted twice
+	if becameOnline {
+		timeToOnline := time.Since(gameServer.CreationTimestamp.Time)
+		gameServerTimeToOnline.Observe(timeToOnline.Seconds())
+
+		notify(ctx, Notification{
+			Event:        NotificationGameServerOnline,
+			Title:        "Game server online",
+			Message:      fmt.Sprintf("Game server %s is online after %v.", gameServer.Name, timeToOnline.Round(time.Second)),
+			GameServerId: id,
+			Fields:       append(gameServerNotificationFields(gameServer), NotificationField{Name: "Host", Value: fmt.Sprintf("%s:%d", status.Host, status.Port)}),
+		})
+	}
+
+	return nil
+}
+
+// updateGameServerDrainStatus sets the draining phase on the deleted game server, the rest of the status is kept as it
+// was observed before the deletion
+func updateGameServerDrainStatus(ctx context.Context, gameServer *veverseV1.GameServer, drainStartedAt time.Time) error {
+	namespace := ctx.Value("namespace").(string)
+	veverseClientset := ctx.Value("veverseClientset").(*versioned.Clientset)
+
+	if gameServer.Status.Phase == veverseV1.GameServerPhaseDraining && gameServer.Status.DrainStartedAt != nil {
+		return nil
+	}
+
+	gameServer = gameServer.DeepCopy()
+	gameServer.Status.Phase = veverseV1.GameServerPhaseDraining
+	startedAt := metaV1.NewTime(drainStartedAt)
+	gameServer.Status.DrainStartedAt = &startedAt
+
+	_, err := veverseClientset.VeverseV1().GameServers(namespace).UpdateStatus(ctx, gameServer, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update game server status: %v", err)
+	}
+
+	return nil
+}
+
+// setGameServerCondition sets the condition on the status, the transition time only changes if the condition status changes
+func setGameServerCondition(status *veverseV1.GameServerStatus, conditionType string, conditionStatus metaV1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metaV1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: status.ObservedGeneration,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"time"
+	veverseInformers "veverse-server-operator/pkg/client/informers/externalversions/veverse/v1"
+	veverseListers "veverse-server-operator/pkg/client/listers/veverse/v1"
+)
+
+// defaultOrphanGracePeriod is the time a deployment or service has to stay orphaned before it is deleted
+const defaultOrphanGracePeriod = 5 * time.Minute
+
+// orphan is an operator managed deployment or service without a matching game server resource
+type orphan struct {
+	Kind string
+	Name string
+	Id   uuid.UUID
+}
+
+// Sweeper periodically deletes operator managed deployments and services that do not match any game server resource,
+// e.g. left behind if the game server has been deleted while the operator was down and the garbage collector could not
+// clean them up
+type Sweeper struct {
+	gameServerLister veverseListers.GameServerLister
+	gameServerSynced cache.InformerSynced
+	interval         time.Duration
+	gracePeriod      time.Duration
+	dryRun           bool
+
+	// first time each orphan has been seen, orphans are only deleted after the grace period
+	orphanedAt map[orphan]time.Time
+}
+
+func NewSweeper(gameServerInformer veverseInformers.GameServerInformer, interval time.Duration, gracePeriod time.Duration, dryRun bool) *Sweeper {
+	return &Sweeper{
+		gameServerLister: gameServerInformer.Lister(),
+		gameServerSynced: gameServerInformer.Informer().HasSynced,
+		interval:         interval,
+		gracePeriod:      gracePeriod,
+		dryRun:           dryRun,
+		orphanedAt:       map[orphan]time.Time{},
+	}
+}
+
+// Run sweeps orphans every interval until the context is cancelled
+func (s *Sweeper) Run(ctx context.Context) {
+	if !cache.WaitForCacheSync(ctx.Done(), s.gameServerSynced) {
+		Logger.Errorf("failed to sync game server informer cache, orphan sweeper is not running")
+		return
+	}
+
+	Logger.Infof("orphan sweeper interval: %v, grace period: %v, dry run: %v", s.interval, s.gracePeriod, s.dryRun)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		start := time.Now()
+		err := s.Sweep(ctx)
+		observeReconcile("orphan_sweep", start, err)
+		if err != nil {
+			Logger.Errorf("failed to sweep orphans: %v", err)
+		}
+	}, s.interval)
+}
+
+// Sweep deletes all orphans that have been orphaned for longer than the grace period
+func (s *Sweeper) Sweep(ctx context.Context) error {
+	orphans, err := s.findOrphans(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	// forget orphans which have been deleted or got their game server back
+	found := map[orphan]bool{}
+	for _, o := range orphans {
+		found[o] = true
+	}
+	for o := range s.orphanedAt {
+		if !found[o] {
+			delete(s.orphanedAt, o)
+		}
+	}
+
+	for _, o := range orphans {
+		orphanedAt, ok := s.orphanedAt[o]
+		if !ok {
+			s.orphanedAt[o] = now
+			Logger.Infof("found orphaned %s %s, deleting after %v", o.Kind, o.Name, s.gracePeriod)
+			continue
+		}
+
+		if now.Sub(orphanedAt) < s.gracePeriod {
+			continue
+		}
+
+		err = s.deleteOrphan(ctx, o, now.Sub(orphanedAt))
+		if err != nil {
+			Logger.Errorf("failed to delete orphaned %s %s: %v", o.Kind, o.Name, err)
+			continue
+		}
+
+		if !s.dryRun {
+			delete(s.orphanedAt, o)
+		}
+	}
+
+	return nil
+}
+
+// findOrphans lists the operator managed deployments and services and returns those which have no game server resource
+// and no active game server record
+func (s *Sweeper) findOrphans(ctx context.Context) ([]orphan, error) {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	listOptions := metaV1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", LabelManagedBy, managedByOperator)}
+
+	var candidates []orphan
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+
+	for _, deployment := range deployments.Items {
+		candidates = append(candidates, orphan{Kind: "deployment", Name: deployment.Name, Id: uuid.FromStringOrNil(deployment.Labels[LabelGameServerId])})
+	}
+
+	services, err := clientset.CoreV1().Services(namespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %v", err)
+	}
+
+	for _, service := range services.Items {
+		candidates = append(candidates, orphan{Kind: "service", Name: service.Name, Id: uuid.FromStringOrNil(service.Labels[LabelGameServerId])})
+	}
+
+	var orphans []orphan
+	for _, candidate := range candidates {
+		if candidate.Id == uuid.Nil {
+			Logger.Warningf("%s %s has no valid game server id label, skipping", candidate.Kind, candidate.Name)
+			continue
+		}
+
+		_, err := s.gameServerLister.GameServers(namespace).Get(candidate.Id.String())
+		if err == nil {
+			continue
+		}
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get game server: %v", err)
+		}
+
+		// an active record without a game server is handled by the controller, which marks it offline first
+		gameServerRecord, err := GetGameServer(ctx, candidate.Id)
+		if err != nil {
+			return nil, err
+		}
+		if gameServerRecord != nil && isGameServerActive(gameServerRecord.Status) {
+			continue
+		}
+
+		orphans = append(orphans, candidate)
+	}
+
+	return orphans, nil
+}
+
+func (s *Sweeper) deleteOrphan(ctx context.Context, o orphan, orphanedFor time.Duration) error {
+	entry := Logger.WithFields(logrus.Fields{
+		"event":        "orphan_deleted",
+		"kind":         o.Kind,
+		"name":         o.Name,
+		"gameServerId": o.Id.String(),
+		"orphanedFor":  orphanedFor.String(),
+		"dryRun":       s.dryRun,
+	})
+
+	if s.dryRun {
+		entry.Infof("dry run, not deleting orphaned %s %s", o.Kind, o.Name)
+		return nil
+	}
+
+	var err error
+	switch o.Kind {
+	case "deployment":
+		err = deleteGameServerDeploymentClusterResource(ctx, o.Id)
+	case "service":
+		err = deleteGameServerServiceClusterResource(ctx, o.Id)
+	default:
+		err = fmt.Errorf("unknown kind %s", o.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	orphansDeletedTotal.WithLabelValues(o.Kind).Inc()
+	entry.Infof("deleted orphaned %s %s", o.Kind, o.Name)
+
+	notify(ctx, Notification{
+		Event:        NotificationOrphanDeleted,
+		Severity:     NotificationSeverityWarning,
+		Title:        "Orphan deleted",
+		Message:      fmt.Sprintf("Deleted %s %s without a game server.", o.Kind, o.Name),
+		GameServerId: o.Id,
+		Fields:       []NotificationField{{Name: "Orphaned for", Value: orphanedFor.Round(time.Second).String()}},
+	})
+
+	return nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"golang.org/x/crypto/ssh"
+	apiV1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"path/filepath"
+	"strings"
+	"time"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// gameServerTokenIssuer is the issuer claim of the game server tokens
+	gameServerTokenIssuer = "veverse-server-operator"
+	// gameServerTokenAudience is the audience claim of the game server tokens, the VeVerse API
+	gameServerTokenAudience = "veverse-api"
+	// gameServerTokenScope limits the token to the game server endpoints of the API
+	gameServerTokenScope = "gameserver"
+
+	defaultGameServerTokenTtl          = 24 * time.Hour
+	defaultGameServerTokenRotateBefore = 6 * time.Hour
+)
+
+const (
+	// AnnotationTokenId is the id of the token stored in the game server token secret
+	AnnotationTokenId = "veverse.com/token-id"
+	// AnnotationTokenExpiresAt is the expiry of the token stored in the game server token secret
+	AnnotationTokenExpiresAt = "veverse.com/token-expires-at"
+
+	// gameServerTokenKey is the key of the token in the game server token secret
+	gameServerTokenKey = "token"
+	// gameServerTokenVolumeName is the name of the volume of the game server token secret
+	gameServerTokenVolumeName = "api-token"
+	// EnvServerApiTokenFile is the file the game server reads its api token from, it is replaced when the token is rotated
+	EnvServerApiTokenFile = "VE_SERVER_API_TOKEN_FILE"
+
+	// gameServerTokenMountPath is the directory the game server token secret is mounted to, mounted secrets are
+	// updated by the kubelet when the token is rotated, unlike env variables
+	gameServerTokenMountPath = "/var/run/secrets/veverse.com"
+)
+
+// gameServerTokens mints the API tokens of game servers, nil if game server tokens are disabled
+var gameServerTokens *TokenIssuer
+
+// TokenIssuer mints short-lived JWTs for game servers signed with the operator private key, the API verifies them
+// with the public key and rejects tokens revoked in the game_server_tokens table
+type TokenIssuer struct {
+	signer    crypto.Signer
+	algorithm string
+	keyId     string
+	// ttl is the lifetime of the tokens
+	ttl time.Duration
+	// rotateBefore is the time before expiry tokens are replaced
+	rotateBefore time.Duration
+}
+
+// gameServerTokenClaims are the claims of a game server token, the subject is the game server id
+type gameServerTokenClaims struct {
+	Id        string `json:"jti"`
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	Scope     string `json:"scope"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	ExpiresAt int64  `json:"exp"`
+	// World and release the game server is allowed to report for
+	WorldId   string `json:"worldId,omitempty"`
+	ReleaseId string `json:"releaseId,omitempty"`
+}
+
+// NewTokenIssuer parses the PEM encoded private key, RSA keys sign RS256 tokens, P-256 keys ES256 and Ed25519 keys
+// EdDSA. OpenSSH, PKCS#1, SEC 1 and PKCS#8 keys are supported, escaped line breaks are replaced.
+func NewTokenIssuer(privateKey string, ttl time.Duration, rotateBefore time.Duration) (*TokenIssuer, error) {
+	if rotateBefore >= ttl {
+		return nil, fmt.Errorf("token rotation %v must be shorter than the token ttl %v", rotateBefore, ttl)
+	}
+
+	rawKey, err := ssh.ParseRawPrivateKey([]byte(strings.ReplaceAll(privateKey, `\n`, "\n")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	issuer := &TokenIssuer{ttl: ttl, rotateBefore: rotateBefore}
+	switch key := rawKey.(type) {
+	case *rsa.PrivateKey:
+		issuer.signer, issuer.algorithm = key, "RS256"
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported private key curve %s", key.Curve.Params().Name)
+		}
+		issuer.signer, issuer.algorithm = key, "ES256"
+	case *ed25519.PrivateKey:
+		issuer.signer, issuer.algorithm = *key, "EdDSA"
+	case ed25519.PrivateKey:
+		issuer.signer, issuer.algorithm = key, "EdDSA"
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", rawKey)
+	}
+
+	// the key id lets the API select the public key while keys are rotated
+	publicKey, err := x509.MarshalPKIXPublicKey(issuer.signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %v", err)
+	}
+	keyHash := sha256.Sum256(publicKey)
+	issuer.keyId = hex.EncodeToString(keyHash[:8])
+
+	return issuer, nil
+}
+
+// Mint returns a signed token for the game server, its id and expiry
+func (i *TokenIssuer) Mint(id uuid.UUID, gameServer *veverseV1.GameServer) (string, uuid.UUID, time.Time, error) {
+	tokenId, err := uuid.NewV4()
+	if err != nil {
+		return "", uuid.Nil, time.Time{}, fmt.Errorf("failed to generate token id: %v", err)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(i.ttl)
+
+	header, err := json.Marshal(map[string]string{"alg": i.algorithm, "typ": "JWT", "kid": i.keyId})
+	if err != nil {
+		return "", uuid.Nil, time.Time{}, fmt.Errorf("failed to encode token header: %v", err)
+	}
+
+	claims, err := json.Marshal(gameServerTokenClaims{
+		Id:        tokenId.String(),
+		Issuer:    gameServerTokenIssuer,
+		Subject:   id.String(),
+		Audience:  gameServerTokenAudience,
+		Scope:     gameServerTokenScope,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		WorldId:   gameServer.Spec.Settings.World.Id,
+		ReleaseId: gameServer.Spec.Settings.Release.Id,
+	})
+	if err != nil {
+		return "", uuid.Nil, time.Time{}, fmt.Errorf("failed to encode token claims: %v", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	signature, err := i.sign([]byte(signingInput))
+	if err != nil {
+		return "", uuid.Nil, time.Time{}, fmt.Errorf("failed to sign token: %v", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), tokenId, expiresAt, nil
+}
+
+// sign returns the JWS signature of the data for the algorithm of the key
+func (i *TokenIssuer) sign(data []byte) ([]byte, error) {
+	switch key := i.signer.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		// JWS signatures are the fixed size concatenation of r and s instead of ASN.1
+		digest := sha256.Sum256(data)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		signature := make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(key, data), nil
+	default:
+		return nil, fmt.Errorf("unsupported signer %T", i.signer)
+	}
+}
+
+func getGameServerTokenSecretName(id uuid.UUID) string {
+	return getResourceName(id) + "-api-token"
+}
+
+// reconcileGameServerToken mints a token for the game server if its token secret is missing or the token expires
+// within the rotation period. The previous token stays valid until it expires, so the game server can use it until the
+// kubelet has updated the mounted secret.
+func reconcileGameServerToken(ctx context.Context, id uuid.UUID, gameServer *veverseV1.GameServer) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	secrets := clientset.CoreV1().Secrets(namespace)
+	secretName := getGameServerTokenSecretName(id)
+
+	secret, err := secrets.Get(ctx, secretName, metaV1.GetOptions{})
+	notFound := errors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("failed to get token secret: %v", err)
+	}
+
+	if !notFound {
+		expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationTokenExpiresAt])
+		if err == nil && time.Until(expiresAt) > gameServerTokens.rotateBefore {
+			return nil
+		}
+	}
+
+	token, tokenId, expiresAt, err := gameServerTokens.Mint(id, gameServer)
+	if err != nil {
+		return err
+	}
+
+	// the token is recorded before it is handed out, so it can always be revoked
+	err = InsertGameServerToken(ctx, tokenId, id, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]string{
+		AnnotationTokenId:        tokenId.String(),
+		AnnotationTokenExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+	}
+	data := map[string][]byte{gameServerTokenKey: []byte(token)}
+
+	if notFound {
+		Logger.Infof("creating api token of game server %s", id)
+		_, err = secrets.Create(ctx, &apiV1.Secret{
+			ObjectMeta: metaV1.ObjectMeta{
+				Name:            secretName,
+				Labels:          getGameServerLabels(id),
+				Annotations:     annotations,
+				OwnerReferences: []metaV1.OwnerReference{getGameServerOwnerReference(gameServer)},
+			},
+			Type: apiV1.SecretTypeOpaque,
+			Data: data,
+		}, metaV1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create token secret: %v", err)
+		}
+		return nil
+	}
+
+	Logger.Infof("rotating api token of game server %s", id)
+	secret = secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	for key, value := range annotations {
+		secret.Annotations[key] = value
+	}
+	secret.Data = data
+
+	_, err = secrets.Update(ctx, secret, metaV1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update token secret: %v", err)
+	}
+
+	return nil
+}
+
+// revokeGameServerTokens revokes the tokens of the game server and deletes its token secret
+func revokeGameServerTokens(ctx context.Context, id uuid.UUID) error {
+	clientset := ctx.Value("clientset").(*kubernetes.Clientset)
+	namespace := ctx.Value("namespace").(string)
+
+	err := RevokeGameServerTokens(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = clientset.CoreV1().Secrets(namespace).Delete(ctx, getGameServerTokenSecretName(id), metaV1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete token secret: %v", err)
+	}
+
+	return nil
+}
+
+// applyGameServerToken mounts the token secret into the game server container, the path of the token file is passed in
+// the env
+func applyGameServerToken(podSpec *apiV1.PodSpec, container *apiV1.Container, id uuid.UUID) {
+	podSpec.Volumes = append(podSpec.Volumes, apiV1.Volume{
+		Name: gameServerTokenVolumeName,
+		VolumeSource: apiV1.VolumeSource{
+			Secret: &apiV1.SecretVolumeSource{
+				SecretName: getGameServerTokenSecretName(id),
+				Items:      []apiV1.KeyToPath{{Key: gameServerTokenKey, Path: gameServerTokenKey}},
+			},
+		},
+	})
+
+	container.VolumeMounts = append(container.VolumeMounts, apiV1.VolumeMount{
+		Name:      gameServerTokenVolumeName,
+		MountPath: gameServerTokenMountPath,
+		ReadOnly:  true,
+	})
+
+	container.Env = append(container.Env, apiV1.EnvVar{Name: EnvServerApiTokenFile, Value: filepath.Join(gameServerTokenMountPath, gameServerTokenKey)})
+}
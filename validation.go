@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	apiV1 "k8s.io/api/core/v1"
+	apiValidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"regexp"
+	"strings"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const (
+	// defaultMaxPlayersLimit is the default upper bound of settings.players.max
+	defaultMaxPlayersLimit = 1000
+	// dockerHubRegistry is the registry of image references without a registry domain
+	dockerHubRegistry = "docker.io"
+	// maxImageNameLength is the maximum length of the image name without tag and digest
+	maxImageNameLength = 255
+)
+
+// imageReferenceRegexp matches image references, following the grammar of github.com/distribution/reference without IPv6
+// registry addresses: [domain[:port]/]path[:tag][@digest]
+var imageReferenceRegexp = func() *regexp.Regexp {
+	alphanumeric := `[a-z0-9]+`
+	separator := `(?:[._]|__|[-]+)`
+	pathComponent := alphanumeric + `(?:` + separator + alphanumeric + `)*`
+	domainComponent := `(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])`
+	domain := domainComponent + `(?:\.` + domainComponent + `)*(?::[0-9]+)?`
+	tag := `[\w][\w.-]{0,127}`
+	digest := `[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9A-Fa-f]{32,}`
+
+	return regexp.MustCompile(`^((?:` + domain + `/)?` + pathComponent + `(?:/` + pathComponent + `)*)(?::` + tag + `)?(?:@` + digest + `)?$`)
+}()
+
+// GameServerValidator validates game server specs before they are stored
+type GameServerValidator struct {
+	// allowedRegistries are the registries game server images can be pulled from, any registry is allowed if empty
+	allowedRegistries []string
+	maxPlayersLimit   int64
+}
+
+func NewGameServerValidator(allowedRegistries []string, maxPlayersLimit int64) *GameServerValidator {
+	return &GameServerValidator{
+		allowedRegistries: allowedRegistries,
+		maxPlayersLimit:   maxPlayersLimit,
+	}
+}
+
+// Validate returns all problems of the game server, the operator is not able to start game servers with an invalid spec
+func (v *GameServerValidator) Validate(gameServer *veverseV1.GameServer) field.ErrorList {
+	var errs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	settingsPath := specPath.Child("settings")
+
+	//region Id
+	// deployments and services are named after the spec id, which has to match the resource name
+	idPath := specPath.Child("id")
+	if gameServer.Spec.Id == "" {
+		errs = append(errs, field.Required(idPath, "game server id is required"))
+	} else if err := validateUuid(gameServer.Spec.Id); err != nil {
+		errs = append(errs, field.Invalid(idPath, gameServer.Spec.Id, err.Error()))
+	} else if gameServer.Name != gameServer.Spec.Id {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), gameServer.Name, "must match spec.id"))
+	}
+	//endregion
+
+	//region Settings
+	if name := gameServer.Spec.Settings.Api.CredentialsSecret; name != "" {
+		for _, message := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(settingsPath.Child("api", "credentialsSecret"), name, message))
+		}
+	}
+
+	releaseIdPath := settingsPath.Child("release", "id")
+	if gameServer.Spec.Settings.Release.Id == "" {
+		errs = append(errs, field.Required(releaseIdPath, "release id is required"))
+	} else if err := validateUuid(gameServer.Spec.Settings.Release.Id); err != nil {
+		errs = append(errs, field.Invalid(releaseIdPath, gameServer.Spec.Settings.Release.Id, err.Error()))
+	}
+
+	if id := gameServer.Spec.Settings.App.Id; id != "" {
+		if err := validateUuid(id); err != nil {
+			errs = append(errs, field.Invalid(settingsPath.Child("app", "id"), id, err.Error()))
+		}
+	}
+
+	if id := gameServer.Spec.Settings.World.Id; id != "" {
+		if err := validateUuid(id); err != nil {
+			errs = append(errs, field.Invalid(settingsPath.Child("world", "id"), id, err.Error()))
+		}
+	}
+
+	maxPlayersPath := settingsPath.Child("players", "max")
+	if maxPlayers := gameServer.Spec.Settings.Players.Max; maxPlayers < 1 || maxPlayers > v.maxPlayersLimit {
+		errs = append(errs, field.Invalid(maxPlayersPath, maxPlayers, fmt.Sprintf("must be between 1 and %d", v.maxPlayersLimit)))
+	}
+
+	imagePath := settingsPath.Child("server", "image")
+	if image := gameServer.Spec.Settings.Server.Image; image == "" {
+		errs = append(errs, field.Required(imagePath, "game server image is required"))
+	} else if err := validateImageReference(image); err != nil {
+		errs = append(errs, field.Invalid(imagePath, image, err.Error()))
+	} else if registry := getImageRegistry(image); !v.isRegistryAllowed(registry) {
+		errs = append(errs, field.Forbidden(imagePath, fmt.Sprintf("registry %s is not allowed, allowed registries: %s", registry, strings.Join(v.allowedRegistries, ", "))))
+	}
+
+	if exposure := gameServer.Spec.Settings.Server.Exposure; exposure != nil {
+		exposurePath := settingsPath.Child("server", "exposure")
+		if _, ok := portExposures[exposure.Type]; exposure.Type != "" && !ok {
+			errs = append(errs, field.NotSupported(exposurePath.Child("type"), exposure.Type, []string{
+				string(veverseV1.ExposureTypeNodePort),
+				string(veverseV1.ExposureTypeHostPort),
+				string(veverseV1.ExposureTypeLoadBalancer),
+				string(veverseV1.ExposureTypePodIP),
+			}))
+		}
+		errs = append(errs, apiValidation.ValidateAnnotations(exposure.Annotations, exposurePath.Child("annotations"))...)
+	}
+
+	portsPath := settingsPath.Child("server", "ports")
+	names := map[string]bool{gameServerPortName: true}
+	// the unreal server port is declared on the container along with the ports of the spec
+	containerPorts := map[string]bool{fmt.Sprintf("%d/%s", gameServerPort, apiV1.ProtocolUDP): true}
+	for i, port := range gameServer.Spec.Settings.Server.Ports {
+		portPath := portsPath.Index(i)
+		if port.Name == "" {
+			errs = append(errs, field.Required(portPath.Child("name"), "port name is required"))
+		} else if messages := validation.IsValidPortName(port.Name); len(messages) > 0 {
+			errs = append(errs, field.Invalid(portPath.Child("name"), port.Name, strings.Join(messages, ", ")))
+		} else if names[port.Name] {
+			errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		names[port.Name] = true
+
+		if messages := validation.IsValidPortNum(int(port.ContainerPort)); len(messages) > 0 {
+			errs = append(errs, field.Invalid(portPath.Child("containerPort"), port.ContainerPort, strings.Join(messages, ", ")))
+		}
+
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = apiV1.ProtocolUDP
+		}
+		if protocol != apiV1.ProtocolUDP && protocol != apiV1.ProtocolTCP {
+			errs = append(errs, field.NotSupported(portPath.Child("protocol"), port.Protocol, []string{string(apiV1.ProtocolUDP), string(apiV1.ProtocolTCP)}))
+		}
+
+		if containerPort := fmt.Sprintf("%d/%s", port.ContainerPort, protocol); containerPorts[containerPort] {
+			errs = append(errs, field.Duplicate(portPath.Child("containerPort"), containerPort))
+		} else {
+			containerPorts[containerPort] = true
+		}
+
+		if port.Policy != "" && port.Policy != veverseV1.PortPolicyPublic && port.Policy != veverseV1.PortPolicyInternal {
+			errs = append(errs, field.NotSupported(portPath.Child("policy"), port.Policy, []string{string(veverseV1.PortPolicyPublic), string(veverseV1.PortPolicyInternal)}))
+		}
+	}
+	//endregion
+
+	return errs
+}
+
+// Warnings returns deprecated settings of the game server, which are accepted but should be changed
+func (v *GameServerValidator) Warnings(gameServer *veverseV1.GameServer) []string {
+	var warnings []string
+
+	apiPath := field.NewPath("spec", "settings", "api")
+	if gameServer.Spec.Settings.Api.V1.Key != "" {
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated, store the key in the secret referenced by %s", apiPath.Child("v1", "key"), apiPath.Child("credentialsSecret")))
+	}
+	if gameServer.Spec.Settings.Api.V2.Password != "" {
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated, store the password in the secret referenced by %s", apiPath.Child("v2", "password"), apiPath.Child("credentialsSecret")))
+	}
+
+	return warnings
+}
+
+func (v *GameServerValidator) isRegistryAllowed(registry string) bool {
+	if len(v.allowedRegistries) == 0 {
+		return true
+	}
+
+	for _, allowed := range v.allowedRegistries {
+		if registry == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateUuid checks that the value is a UUID in its canonical lowercase form, which is a valid resource name
+func validateUuid(value string) error {
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return fmt.Errorf("must be a UUID")
+	}
+
+	if id.String() != value {
+		return fmt.Errorf("must be a lowercase UUID with hyphens")
+	}
+
+	return nil
+}
+
+// validateImageReference checks the image reference syntax
+func validateImageReference(image string) error {
+	match := imageReferenceRegexp.FindStringSubmatch(image)
+	if match == nil {
+		return fmt.Errorf("must be a valid image reference")
+	}
+
+	if len(match[1]) > maxImageNameLength {
+		return fmt.Errorf("image name must not be longer than %d characters", maxImageNameLength)
+	}
+
+	return nil
+}
+
+// getImageRegistry returns the registry domain of a valid image reference, the first path component is a domain if it
+// contains a dot or a port or is localhost, like in the docker cli
+func getImageRegistry(image string) string {
+	domain, _, found := strings.Cut(image, "/")
+	if !found || !(strings.ContainsAny(domain, ".:") || domain == "localhost") {
+		return dockerHubRegistry
+	}
+
+	return domain
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	apiV1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+const validationTestId = "2f4a6c5e-8b1d-4e3f-9a7b-0c1d2e3f4a5b"
+
+// newValidationTestGameServer returns a game server which passes the validation
+func newValidationTestGameServer() *veverseV1.GameServer {
+	gameServer := &veverseV1.GameServer{
+		ObjectMeta: metaV1.ObjectMeta{Name: validationTestId},
+		Spec: veverseV1.GameServerSpec{
+			Id: validationTestId,
+		},
+	}
+	gameServer.Spec.Settings.Release.Id = "6b8a1f2e-3c4d-4e5f-8a9b-1c2d3e4f5a6b"
+	gameServer.Spec.Settings.Players.Max = 100
+	gameServer.Spec.Settings.Server.Image = "registry.example.com/veverse/server:1.0.0"
+
+	return gameServer
+}
+
+func TestGameServerValidatorValidate(t *testing.T) {
+	validator := NewGameServerValidator([]string{"registry.example.com", dockerHubRegistry}, 200)
+
+	tests := []struct {
+		name   string
+		modify func(gameServer *veverseV1.GameServer)
+		// fields of the expected errors
+		want []string
+	}{
+		{name: "valid", modify: func(*veverseV1.GameServer) {}},
+		{name: "missing id", modify: func(gs *veverseV1.GameServer) { gs.Spec.Id = "" }, want: []string{"spec.id"}},
+		{name: "uppercase id", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Id = "2F4A6C5E-8B1D-4E3F-9A7B-0C1D2E3F4A5B"
+			gs.Name = gs.Spec.Id
+		}, want: []string{"spec.id"}},
+		{name: "name does not match id", modify: func(gs *veverseV1.GameServer) { gs.Name = "gameserver" }, want: []string{"metadata.name"}},
+		{name: "invalid credentials secret", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Api.CredentialsSecret = "Api_Credentials" }, want: []string{"spec.settings.api.credentialsSecret"}},
+		{name: "missing release", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Release.Id = "" }, want: []string{"spec.settings.release.id"}},
+		{name: "invalid app", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.App.Id = "app" }, want: []string{"spec.settings.app.id"}},
+		{name: "invalid world", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.World.Id = "world" }, want: []string{"spec.settings.world.id"}},
+		{name: "no players", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Players.Max = 0 }, want: []string{"spec.settings.players.max"}},
+		{name: "players above limit", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Players.Max = 201 }, want: []string{"spec.settings.players.max"}},
+		{name: "players at limit", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Players.Max = 200 }},
+		{name: "missing image", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Server.Image = "" }, want: []string{"spec.settings.server.image"}},
+		{name: "invalid image", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Server.Image = "Veverse/Server" }, want: []string{"spec.settings.server.image"}},
+		{name: "docker hub image", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Server.Image = "veverse/server:1.0.0" }},
+		{name: "registry not allowed", modify: func(gs *veverseV1.GameServer) { gs.Spec.Settings.Server.Image = "ghcr.io/veverse/server:1.0.0" }, want: []string{"spec.settings.server.image"}},
+		{name: "exposure", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Exposure = &veverseV1.ExposureSettings{Type: veverseV1.ExposureTypeLoadBalancer, Annotations: map[string]string{"example.com/load-balancer-type": "udp"}}
+		}},
+		{name: "unknown exposure", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Exposure = &veverseV1.ExposureSettings{Type: "Ingress"}
+		}, want: []string{"spec.settings.server.exposure.type"}},
+		{name: "invalid exposure annotation", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Exposure = &veverseV1.ExposureSettings{Annotations: map[string]string{"invalid key": "value"}}
+		}, want: []string{"spec.settings.server.exposure.annotations"}},
+		{name: "ports", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{
+				{Name: "beacon", ContainerPort: 15000},
+				{Name: "query", Protocol: apiV1.ProtocolTCP, ContainerPort: 7777, Policy: veverseV1.PortPolicyInternal},
+			}
+		}},
+		{name: "port named like the unreal server port", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: gameServerPortName, ContainerPort: 15000}}
+		}, want: []string{"spec.settings.server.ports[0].name"}},
+		{name: "duplicate port name", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "beacon", ContainerPort: 15000}, {Name: "beacon", ContainerPort: 15001}}
+		}, want: []string{"spec.settings.server.ports[1].name"}},
+		{name: "missing port name", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{ContainerPort: 15000}}
+		}, want: []string{"spec.settings.server.ports[0].name"}},
+		{name: "invalid port name", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "voice_chat", ContainerPort: 15000}}
+		}, want: []string{"spec.settings.server.ports[0].name"}},
+		{name: "invalid container port", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "beacon", ContainerPort: 0}}
+		}, want: []string{"spec.settings.server.ports[0].containerPort"}},
+		{name: "container port of the unreal server port", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "beacon", ContainerPort: gameServerPort}}
+		}, want: []string{"spec.settings.server.ports[0].containerPort"}},
+		{name: "duplicate container port", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{
+				{Name: "beacon", Protocol: apiV1.ProtocolTCP, ContainerPort: 15000},
+				{Name: "query", Protocol: apiV1.ProtocolTCP, ContainerPort: 15000},
+			}
+		}, want: []string{"spec.settings.server.ports[1].containerPort"}},
+		{name: "unsupported protocol", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "beacon", Protocol: apiV1.ProtocolSCTP, ContainerPort: 15000}}
+		}, want: []string{"spec.settings.server.ports[0].protocol"}},
+		{name: "unsupported policy", modify: func(gs *veverseV1.GameServer) {
+			gs.Spec.Settings.Server.Ports = []veverseV1.GameServerPort{{Name: "beacon", ContainerPort: 15000, Policy: "Private"}}
+		}, want: []string{"spec.settings.server.ports[0].policy"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gameServer := newValidationTestGameServer()
+			test.modify(gameServer)
+
+			var got []string
+			for _, err := range validator.Validate(gameServer) {
+				got = append(got, err.Field)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got errors of %v, want %v: %v", got, test.want, validator.Validate(gameServer))
+			}
+		})
+	}
+}
+
+func TestGameServerValidatorAllowsAnyRegistry(t *testing.T) {
+	validator := NewGameServerValidator(nil, 200)
+
+	gameServer := newValidationTestGameServer()
+	gameServer.Spec.Settings.Server.Image = "ghcr.io/veverse/server:1.0.0"
+
+	if errs := validator.Validate(gameServer); len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestGameServerValidatorWarnings(t *testing.T) {
+	validator := NewGameServerValidator(nil, 200)
+
+	gameServer := newValidationTestGameServer()
+	if warnings := validator.Warnings(gameServer); len(warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	gameServer.Spec.Settings.Api.V1.Key = "key"
+	gameServer.Spec.Settings.Api.V2.Password = "password"
+	if warnings := validator.Warnings(gameServer); len(warnings) != 2 {
+		t.Errorf("got %d warnings, want 2: %v", len(warnings), warnings)
+	}
+}
+
+func TestValidateImageReference(t *testing.T) {
+	tests := []struct {
+		image string
+		valid bool
+	}{
+		{image: "server", valid: true},
+		{image: "veverse/server:1.0.0", valid: true},
+		{image: "registry.example.com/veverse/server:1.0.0", valid: true},
+		{image: "registry.example.com:5000/veverse/server", valid: true},
+		{image: "localhost/server:latest", valid: true},
+		{image: "veverse/server@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", valid: true},
+		{image: "veverse/server:1.0.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", valid: true},
+		{image: "Veverse/Server", valid: false},
+		{image: "veverse/server:", valid: false},
+		{image: "veverse//server", valid: false},
+		{image: "veverse/server@sha256:short", valid: false},
+		{image: "-veverse/server", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			err := validateImageReference(test.image)
+			if test.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestGetImageRegistry(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "server", want: dockerHubRegistry},
+		{image: "veverse/server:1.0.0", want: dockerHubRegistry},
+		{image: "registry.example.com/veverse/server", want: "registry.example.com"},
+		{image: "registry:5000/server", want: "registry:5000"},
+		{image: "localhost/server", want: "localhost"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			if got := getImageRegistry(test.image); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidateUuid(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: validationTestId, valid: true},
+		{value: "2F4A6C5E-8B1D-4E3F-9A7B-0C1D2E3F4A5B", valid: false},
+		{value: "2f4a6c5e8b1d4e3f9a7b0c1d2e3f4a5b", valid: false},
+		{value: "gameserver", valid: false},
+		{value: "", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			err := validateUuid(test.value)
+			if test.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
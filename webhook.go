@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	admissionV1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"net/http"
+	veverseV1 "veverse-server-operator/pkg/apis/veverse/v1"
+)
+
+// maxAdmissionReviewSize limits the admission review request body
+const maxAdmissionReviewSize = 1 << 20
+
+// ValidatingWebhook serves the admission webhook rejecting game servers the operator is not able to start
+type ValidatingWebhook struct {
+	validator *GameServerValidator
+}
+
+func NewValidatingWebhook(validator *GameServerValidator) *ValidatingWebhook {
+	return &ValidatingWebhook{validator: validator}
+}
+
+// ServeHTTP handles admission reviews of game server create and update requests
+func (h *ValidatingWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review, err := readAdmissionReview(w, r)
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := &admissionV1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+
+	gameServer := &veverseV1.GameServer{}
+	err = json.Unmarshal(review.Request.Object.Raw, gameServer)
+	if err != nil {
+		response.Allowed = false
+		response.Result = &metaV1.Status{
+			Status:  metaV1.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Reason:  metaV1.StatusReasonBadRequest,
+			Message: fmt.Sprintf("failed to decode game server: %v", err),
+		}
+	} else if errs := h.validate(review.Request, gameServer); len(errs) > 0 {
+		Logger.Infof("rejected game server %s: %v", gameServer.Name, errs.ToAggregate())
+		response.Allowed = false
+		response.Result = &metaV1.Status{
+			Status:  metaV1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metaV1.StatusReasonInvalid,
+			Message: errs.ToAggregate().Error(),
+		}
+	} else if !isGameServerSpecUnchanged(review.Request, gameServer) {
+		response.Warnings = h.validator.Warnings(gameServer)
+	}
+
+	writeAdmissionReview(w, review, response)
+}
+
+// validate validates created game servers and updated game server specs, game servers created before the webhook must
+// not block finalizer and label updates
+func (h *ValidatingWebhook) validate(request *admissionV1.AdmissionRequest, gameServer *veverseV1.GameServer) field.ErrorList {
+	if isGameServerSpecUnchanged(request, gameServer) {
+		return nil
+	}
+
+	return h.validator.Validate(gameServer)
+}
+
+// isGameServerSpecUnchanged checks if the request updates a game server without changing its spec or deletes it
+func isGameServerSpecUnchanged(request *admissionV1.AdmissionRequest, gameServer *veverseV1.GameServer) bool {
+	if request.Operation != admissionV1.Update {
+		return false
+	}
+
+	if gameServer.DeletionTimestamp != nil {
+		return true
+	}
+
+	oldGameServer := &veverseV1.GameServer{}
+	err := json.Unmarshal(request.OldObject.Raw, oldGameServer)
+	if err != nil {
+		return false
+	}
+
+	return equality.Semantic.DeepEqual(oldGameServer.Spec, gameServer.Spec)
+}
+
+// MutatingWebhook serves the admission webhook setting the defaults of game server specs before they are validated
+type MutatingWebhook struct {
+	defaulter *GameServerDefaulter
+}
+
+func NewMutatingWebhook(defaulter *GameServerDefaulter) *MutatingWebhook {
+	return &MutatingWebhook{defaulter: defaulter}
+}
+
+// ServeHTTP handles admission reviews of game server create and update requests, the defaulted spec is returned as a
+// JSON patch replacing the spec
+func (h *MutatingWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	review, err := readAdmissionReview(w, r)
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := &admissionV1.AdmissionResponse{UID: review.Request.UID, Allowed: true}
+
+	gameServer := &veverseV1.GameServer{}
+	err = json.Unmarshal(review.Request.Object.Raw, gameServer)
+	if err != nil {
+		// malformed game servers are rejected by the validating webhook
+		writeAdmissionReview(w, review, response)
+		return
+	}
+
+	if isGameServerSpecUnchanged(review.Request, gameServer) {
+		writeAdmissionReview(w, review, response)
+		return
+	}
+
+	changed, err := h.defaulter.Default(r.Context(), gameServer)
+	if err != nil {
+		Logger.Errorf("failed to set defaults of game server %s: %v", gameServer.Name, err)
+		response.Allowed = false
+		response.Result = &metaV1.Status{
+			Status:  metaV1.StatusFailure,
+			Code:    http.StatusInternalServerError,
+			Reason:  metaV1.StatusReasonInternalError,
+			Message: fmt.Sprintf("failed to set game server defaults: %v", err),
+		}
+	} else if changed {
+		patch, err := json.Marshal([]jsonPatchOperation{{Op: "replace", Path: "/spec", Value: gameServer.Spec}})
+		if err != nil {
+			writeJsonError(w, http.StatusInternalServerError, fmt.Sprintf("failed to encode patch: %v", err))
+			return
+		}
+
+		patchType := admissionV1.PatchTypeJSONPatch
+		response.Patch = patch
+		response.PatchType = &patchType
+	}
+
+	writeAdmissionReview(w, review, response)
+}
+
+// jsonPatchOperation is a single RFC 6902 JSON patch operation
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// readAdmissionReview decodes the admission review of the request
+func readAdmissionReview(w http.ResponseWriter, r *http.Request) (*admissionV1.AdmissionReview, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("method not allowed")
+	}
+
+	review := &admissionV1.AdmissionReview{}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdmissionReviewSize)).Decode(review)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admission review: %v", err)
+	}
+
+	if review.Request == nil {
+		return nil, fmt.Errorf("admission review has no request")
+	}
+
+	return review, nil
+}
+
+// writeAdmissionReview responds with the admission review of the same version as the request
+func writeAdmissionReview(w http.ResponseWriter, review *admissionV1.AdmissionReview, response *admissionV1.AdmissionResponse) {
+	writeJson(w, http.StatusOK, &admissionV1.AdmissionReview{
+		TypeMeta: review.TypeMeta,
+		Response: response,
+	})
+}